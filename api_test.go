@@ -0,0 +1,140 @@
+package tebo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "123:test"
+
+// fakeAPI is a local imitation of the Bot API server
+type fakeAPI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	methods  map[string]func(r *http.Request) (interface{}, *ErrorResponse)
+	calls    []string
+	requests int
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{methods: make(map[string]func(*http.Request) (interface{}, *ErrorResponse))}
+
+	api.Handle("getMe", func(*http.Request) (interface{}, *ErrorResponse) {
+		return User{ID: 1, IsBot: true, Username: "testbot"}, nil
+	})
+
+	api.Server = httptest.NewServer(api)
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+func (api *fakeAPI) Handle(method string, f func(r *http.Request) (interface{}, *ErrorResponse)) {
+	api.mu.Lock()
+	api.methods[method] = f
+	api.mu.Unlock()
+}
+
+func (api *fakeAPI) Calls() []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]string(nil), api.calls...)
+}
+
+func (api *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/file/bot"+testToken+"/") {
+		fmt.Fprint(w, strings.TrimPrefix(r.URL.Path, "/file/bot"+testToken+"/"))
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/bot"+testToken+"/")
+
+	api.mu.Lock()
+	api.calls = append(api.calls, method)
+	f, ok := api.methods[method]
+	api.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{ErrorCode: 404, Description: "Not Found"})
+		return
+	}
+
+	result, e := f(r)
+	if e != nil {
+		w.WriteHeader(e.ErrorCode)
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	data, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(Response{OK: true, Result: data})
+}
+
+// decodeRequest decode json payload of the request
+func decodeRequest(r *http.Request, v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func newTestBot(t *testing.T, api *fakeAPI, opts ...Option) *Bot {
+	opts = append([]Option{WithAPIURL(api.URL)}, opts...)
+
+	b, err := NewBot(testToken, filepath.Join(t.TempDir(), "history"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(b.Close)
+
+	return b
+}
+
+type countingTransport struct {
+	n int
+	http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.n++
+	return t.RoundTripper.RoundTrip(r)
+}
+
+func TestNewBotOptions(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Handle("getFile", func(r *http.Request) (interface{}, *ErrorResponse) {
+		var req struct {
+			FileID string `json:"file_id"`
+		}
+		decodeRequest(r, &req)
+		return File{FileID: req.FileID, FilePath: "photos/" + req.FileID}, nil
+	})
+
+	tr := &countingTransport{RoundTripper: http.DefaultTransport}
+	b := newTestBot(t, api, WithHTTPClient(&http.Client{Transport: tr}))
+
+	if b.Username != "testbot" {
+		t.Errorf("unexpected bot username %q", b.Username)
+	}
+
+	var buf bytes.Buffer
+	f, err := b.LoadFile("abc", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.FilePath != "photos/abc" || buf.String() != "photos/abc" {
+		t.Errorf("unexpected file %+v, content %q", f, buf.String())
+	}
+
+	// getMe, getFile and file download
+	if tr.n != 3 {
+		t.Errorf("expected 3 requests through custom client, got %d", tr.n)
+	}
+}
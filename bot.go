@@ -7,17 +7,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/imroc/req/v3"
-	"github.com/mitchellh/mapstructure"
 	"github.com/op/go-logging"
 )
 
 var (
-	addr     = "https://api.telegram.org/bot%s/"
-	fileaddr = "https://api.telegram.org/file/bot%s/"
+	// APIURL is default address of the Bot API server
+	APIURL = "https://api.telegram.org"
+
+	addr     = "%s/bot%s/"
+	fileaddr = "%s/file/bot%s/"
 	log      = logging.MustGetLogger("TEBO")
 
 	// Timeout in seconds
@@ -27,14 +31,34 @@ var (
 type Bot struct {
 	User
 
+	apiurl   string
 	addr     string
 	fileaddr string
 
+	client      *req.Client
+	httpClient  *http.Client
+	retryPolicy *RetryPolicy
+	limiter     *limiter
+	workers     int
+	dispatcher  *dispatcher
+
+	mediaGroupWindow time.Duration
+
 	UpdateID int
 
-	historyFile *os.File
+	history       HistoryStore
+	historyPolicy HistoryPolicy
+	historyMu     sync.Mutex
+
+	chatStore   ChatStore
+	chatStoreMu sync.Mutex
+
+	sessions     *sessions
+	sessionsOnce sync.Once
 
 	handlers        []handler
+	kindHandlers    map[string]handler
+	inlineHandlers  []inlineHandler
 	middlewares     []MiddlewareFunc
 	updatesHandlers []UpdatesFunc
 
@@ -43,17 +67,15 @@ type Bot struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	closed bool
+	pollCtx    context.Context
+	stopPoll   context.CancelFunc
+	polling    sync.WaitGroup
+	closed     atomic.Bool
+	shutdownMu sync.Mutex
 }
 
-func NewBot(token, historyfile string) (b *Bot, err error) {
-	b = &Bot{
-		addr:     fmt.Sprintf(addr, token),
-		fileaddr: fmt.Sprintf(fileaddr, token),
-		Chats:    new(chats),
-	}
-
-	b.ctx, b.cancel = context.WithCancel(context.Background())
+func NewBot(token, historyfile string, opts ...Option) (b *Bot, err error) {
+	b = newBot(token, opts...)
 
 	b.User, err = b.GetMe()
 	if err != nil {
@@ -69,13 +91,109 @@ func NewBot(token, historyfile string) (b *Bot, err error) {
 	return
 }
 
+// newBot initialize the bot without any requests to the server
+func newBot(token string, opts ...Option) *Bot {
+	b := &Bot{
+		apiurl:  APIURL,
+		Chats:   new(chats),
+		limiter: newLimiter(DefaultRateLimit),
+		workers: DefaultWorkers,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.addr = fmt.Sprintf(addr, b.apiurl, token)
+	b.fileaddr = fmt.Sprintf(fileaddr, b.apiurl, token)
+	b.client = b.newClient()
+	b.dispatcher = newDispatcher(b, b.workers)
+	if b.mediaGroupWindow > 0 {
+		b.dispatcher.mediaGroups = newMediaGroups(b.dispatcher, b.mediaGroupWindow)
+	}
+
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	b.pollCtx, b.stopPoll = context.WithCancel(b.ctx)
+
+	return b
+}
+
+// Close stop the bot immediately, in-flight handlers are not waited
 func (b *Bot) Close() {
 	if b == nil {
 		return
 	}
 
-	b.closed = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b.Shutdown(ctx)
+}
+
+// ShutdownError is returned by Shutdown if some updates are not processed
+type ShutdownError struct {
+	// Running is number of handlers not finished in time
+	Running int
+	// Pending updates are received but not passed to handlers
+	Pending []Update
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown: abandoned %d running handlers and %d pending updates", e.Running, len(e.Pending))
+}
+
+// Shutdown gracefully stop the bot: stop polling of updates, cancel waiting
+// for answers, wait in-flight handlers until the context is done and close history.
+// If not all updates are processed then ShutdownError is returned
+func (b *Bot) Shutdown(ctx context.Context) (err error) {
+	b.shutdownMu.Lock()
+	defer b.shutdownMu.Unlock()
+
+	b.closed.Store(true)
+	b.stopPoll()
+	b.polling.Wait()
+
+	if b.dispatcher.mediaGroups != nil {
+		b.dispatcher.mediaGroups.flushAll()
+	}
+
+	// handlers waiting for answers will receive false
+	b.Chats.chats.Range(func(_, ch interface{}) bool {
+		ch.(*chat).answer(nil)
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		b.dispatcher.wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	if running, pending := b.dispatcher.abandon(); running > 0 || len(pending) > 0 {
+		err = &ShutdownError{Running: running, Pending: pending}
+	}
+
+	// abort requests of abandoned handlers
 	b.cancel()
+
+	if e := b.closeHistory(); e != nil && err == nil {
+		err = e
+	}
+
+	if e := b.closeChats(); e != nil && err == nil {
+		err = e
+	}
+
+	if e := b.closeSessions(); e != nil && err == nil {
+		err = e
+	}
+
+	return err
 }
 
 func (b *Bot) LookupChatID(name string) (int, bool) {
@@ -90,22 +208,53 @@ func (b *Bot) LookupChatID(name string) (int, bool) {
 type ErrorResponse struct {
 	Status string `json:"-"`
 
-	Ok          bool   `json:"ok"`
-	ErrorCode   int    `json:"error_code"`
-	Description string `json:"description"`
+	Ok          bool                `json:"ok"`
+	ErrorCode   int                 `json:"error_code"`
+	Description string              `json:"description"`
+	Parameters  *ResponseParameters `json:"parameters,omitempty"`
+}
+
+// ResponseParameters describes why a request was unsuccessful
+type ResponseParameters struct {
+	MigrateToChatID int `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      int `json:"retry_after,omitempty"`
 }
 
 func (e *ErrorResponse) Error() string {
+	if e.Description == "" {
+		return e.Status
+	}
+
 	return e.Description
 }
 
+// RetryAfter return duration to wait before the request can be repeated
+func (e *ErrorResponse) RetryAfter() time.Duration {
+	if e.Parameters == nil {
+		return 0
+	}
+
+	return time.Duration(e.Parameters.RetryAfter) * time.Second
+}
+
+// newClient create client used by all requests of the bot,
+// if http client is specified by option then it is used as is
+func (b *Bot) newClient() *req.Client {
+	c := req.C().SetTimeout(time.Duration(Timeout) * time.Second)
+	if b.httpClient != nil {
+		*c.GetClient() = *b.httpClient
+	}
+
+	return c
+}
+
 func (b *Bot) req() *req.Request {
-	return req.C().SetTimeout(time.Duration(Timeout) * time.Second).R()
+	return b.client.R()
 }
 
 func (b *Bot) handleResp(resp *req.Response, v interface{}) (err error) {
 	if resp.IsErrorState() {
-		err = &ErrorResponse{Status: resp.Status}
+		err = &ErrorResponse{Status: resp.Status, ErrorCode: resp.StatusCode}
 		json.NewDecoder(resp.Body).Decode(err)
 		return
 	}
@@ -123,15 +272,54 @@ func (b *Bot) handleResp(resp *req.Response, v interface{}) (err error) {
 }
 
 func (b *Bot) Request(method string, payload, v interface{}) (err error) {
-	resp, err := b.req().
-		SetBodyJsonMarshal(payload).
-		SetContext(b.ctx).
-		Post(b.addr + method)
+	return b.request(b.ctx, method, payload, v)
+}
+
+func (b *Bot) request(ctx context.Context, method string, payload, v interface{}) (err error) {
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	return b.handleResp(resp, v)
+	chatid := payloadChatID(body)
+
+	send := func() error {
+		return b.retry(ctx, method, func() error {
+			if err := b.wait(ctx, method, chatid); err != nil {
+				return err
+			}
+
+			resp, err := b.req().
+				SetBodyJsonBytes(body).
+				SetContext(ctx).
+				Post(b.addr + method)
+			if err != nil {
+				return err
+			}
+
+			return b.handleResp(resp, v)
+		})
+	}
+
+	err = send()
+
+	// request to the group upgraded to supergroup is sent again to the new chat
+	if to, ok := b.followMigration(chatid, err); ok {
+		p, e := newParams(payload)
+		if e != nil {
+			return err
+		}
+		p.set("chat_id", to)
+
+		if body, e = json.Marshal(p); e != nil {
+			return err
+		}
+		chatid = strconv.Itoa(to)
+
+		err = send()
+	}
+
+	return err
 }
 
 type FormFile struct {
@@ -142,38 +330,116 @@ type FormFile struct {
 }
 
 func (b *Bot) FileRequest(method string, file FormFile, payload interface{}, v interface{}) error {
-	q, err := b.encodePayload(payload)
+	return b.FilesRequest(method, []FormFile{file}, payload, v)
+}
+
+// FilesRequest send multipart request with uploaded files,
+// payload fields are passed as form values
+func (b *Bot) FilesRequest(method string, files []FormFile, payload interface{}, v interface{}) error {
+	p, err := newParams(payload)
 	if err != nil {
 		return fmt.Errorf("faield to encode payload: %v", err)
 	}
 
-	log.Debug(q)
+	form := p.form()
+	log.Debug(form)
 
-	resp, err := b.req().
-		SetQueryParamsAnyType(q).
-		SetFileReader(file.field, file.Name, file).
-		SetContext(b.ctx).
-		Post(b.addr + method)
-	if err != nil {
-		return err
+	chatid := form["chat_id"]
+
+	do := func() error {
+		if err := b.wait(b.ctx, method, chatid); err != nil {
+			return err
+		}
+
+		r := b.req().SetFormData(form)
+		for _, file := range files {
+			r.SetFileReader(file.field, file.Name, file)
+		}
+
+		resp, err := r.SetContext(b.ctx).Post(b.addr + method)
+		if err != nil {
+			return err
+		}
+
+		return b.handleResp(resp, v)
+	}
+
+	// files can be sent again only if it is possible to rewind them,
+	// otherwise migration of the chat is only recorded
+	var seekers []io.Seeker
+	for _, file := range files {
+		seeker, ok := file.Reader.(io.Seeker)
+		if !ok {
+			err := do()
+			b.followMigration(chatid, err)
+			return err
+		}
+		seekers = append(seekers, seeker)
+	}
+
+	var attempt int
+	send := func() error {
+		return b.retry(b.ctx, method, func() error {
+			if attempt++; attempt > 1 {
+				for _, seeker := range seekers {
+					if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+						return err
+					}
+				}
+			}
+
+			return do()
+		})
+	}
+
+	err = send()
+
+	// request to the group upgraded to supergroup is sent again to the new chat
+	if to, ok := b.followMigration(chatid, err); ok {
+		chatid = strconv.Itoa(to)
+		form["chat_id"] = chatid
+
+		err = send()
 	}
 
-	return b.handleResp(resp, v)
+	return err
 }
 
-func (b *Bot) encodePayload(payload interface{}) (q map[string]interface{}, err error) {
-	q = make(map[string]interface{})
+// params is payload of the request with json encoded values
+type params map[string]json.RawMessage
 
-	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		TagName: "json",
-		Result:  &q,
-	})
+func newParams(payload interface{}) (params, error) {
+	p := make(params)
+	if payload == nil {
+		return p, nil
+	}
+
+	data, err := json.Marshal(payload)
 	if err != nil {
-		return q, err
+		return p, err
 	}
 
-	err = d.Decode(payload)
-	return
+	return p, json.Unmarshal(data, &p)
+}
+
+func (p params) set(key string, v interface{}) {
+	p[key], _ = json.Marshal(v)
+}
+
+// form return values of the params as strings,
+// objects are passed as json serialized strings
+func (p params) form() map[string]string {
+	form := make(map[string]string, len(p))
+	for key, val := range p {
+		var s string
+		if err := json.Unmarshal(val, &s); err == nil {
+			form[key] = s
+		} else if string(val) != "null" {
+			form[key] = string(val)
+		}
+	}
+
+	return form
 }
 
 func (b *Bot) GetMe() (me User, err error) {
@@ -182,12 +448,21 @@ func (b *Bot) GetMe() (me User, err error) {
 }
 
 type ReqUpdates struct {
-	Offset  int `json:"offset"`
-	Timeout int `json:"timeout"`
+	Offset         int      `json:"offset"`
+	Timeout        int      `json:"timeout"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
 }
 
 func (b *Bot) GetUpdates(offset int) (updates []Update, err error) {
-	err = b.Request("getUpdates", ReqUpdates{Offset: offset, Timeout: Timeout / 2}, &updates)
+	return b.getUpdates(b.ctx, offset)
+}
+
+func (b *Bot) getUpdates(ctx context.Context, offset int) (updates []Update, err error) {
+	err = b.request(ctx, "getUpdates", ReqUpdates{
+		Offset:         offset,
+		Timeout:        Timeout / 2,
+		AllowedUpdates: AllowedUpdates,
+	}, &updates)
 	return
 }
 
@@ -207,11 +482,13 @@ const (
 )
 
 type SendOptions struct {
-	ParseMode string `json:"parse_mode,omitempty"`
+	MessageThreadID int    `json:"message_thread_id,omitempty"`
+	ParseMode       string `json:"parse_mode,omitempty"`
 	// disable_web_page_preview
-	DisableNotification bool `json:"disable_notification,omitempty"`
-	// reply_to_message_id
-	ReplyMarkup interface{} `json:"reply_markup,omitempty"`
+	DisableNotification bool        `json:"disable_notification,omitempty"`
+	ProtectContent      bool        `json:"protect_content,omitempty"`
+	ReplyToMessageID    int         `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup         interface{} `json:"reply_markup,omitempty"`
 }
 
 // type ReplyMarkup struct {
@@ -263,89 +540,111 @@ func (b *Bot) DeleteMessage(chatid int, messageid int) error {
 	}, nil)
 }
 
-type ReqSendPhoto struct {
-	ChatID      int    `json:"chat_id"`
-	Caption     string `json:"caption,omitempty"`
-	SendOptions `json:",omitempty,squash"`
-	// ...
+// DeleteMessages delete 1-100 messages of the chat, messages which can not be deleted are skipped
+func (b *Bot) DeleteMessages(chatid int, messageids []int) error {
+	return b.Request("deleteMessages", map[string]interface{}{
+		"chat_id":     chatid,
+		"message_ids": messageids,
+	}, nil)
 }
 
-func (b *Bot) SendPhoto(chatid int, photo FormFile, caption string, opt ...SendOptions) (msgid int, err error) {
-	req := ReqSendPhoto{
-		ChatID:  chatid,
-		Caption: caption,
-	}
-	if len(opt) > 0 {
-		req.SendOptions = opt[0]
-	}
-	photo.field = "photo"
+//
+// Forward and copy
+//
 
-	var msg Message
-	err = b.FileRequest("sendPhoto", photo, req, &msg)
-	return msg.MessageID, err
+type MessageID struct {
+	MessageID int `json:"message_id"`
 }
 
-func (b *Bot) SendDocument(chatid int, document FormFile, caption string, opt ...SendOptions) (msgid int, err error) {
-	req := ReqSendPhoto{
-		ChatID:  chatid,
-		Caption: caption,
-	}
+type ReqForwardMessage struct {
+	ChatID              int   `json:"chat_id"`
+	FromChatID          int   `json:"from_chat_id"`
+	MessageID           int   `json:"message_id,omitempty"`
+	MessageIDs          []int `json:"message_ids,omitempty"`
+	MessageThreadID     int   `json:"message_thread_id,omitempty"`
+	DisableNotification bool  `json:"disable_notification,omitempty"`
+	ProtectContent      bool  `json:"protect_content,omitempty"`
+}
+
+// newReqForwardMessage return request with options supported by forwarding
+func newReqForwardMessage(to, from int, opt []SendOptions) ReqForwardMessage {
+	req := ReqForwardMessage{ChatID: to, FromChatID: from}
 	if len(opt) > 0 {
-		req.SendOptions = opt[0]
+		req.MessageThreadID = opt[0].MessageThreadID
+		req.DisableNotification = opt[0].DisableNotification
+		req.ProtectContent = opt[0].ProtectContent
 	}
-	document.field = "document"
 
-	var msg Message
-	err = b.FileRequest("sendDocument", document, req, nil)
-	return msg.MessageID, err
+	return req
 }
 
-//
-// EditMessage
-//
+// ForwardMessage forward message with link to the original message,
+// only thread, notification and protection options are used
+func (b *Bot) ForwardMessage(to, from, messageid int, opt ...SendOptions) (msg Message, err error) {
+	req := newReqForwardMessage(to, from, opt)
+	req.MessageID = messageid
 
-type ReqEditMessage struct {
-	ChatID    int `json:"chat_id"`
-	MessageID int `json:"message_id,omitempty"`
-	SendMessage
+	err = b.Request("forwardMessage", req, &msg)
+	return
 }
 
-func (b *Bot) EditMessage(chatid int, messageid int, smsg *SendMessage) (msg Message, err error) {
-	if len(smsg.Text) == 0 {
-		return msg, errors.New("text is empty")
+// ForwardMessages forward 1-100 messages, albums are grouped,
+// return identifiers of the sent messages
+func (b *Bot) ForwardMessages(to, from int, messageids []int, opt ...SendOptions) ([]int, error) {
+	req := newReqForwardMessage(to, from, opt)
+	req.MessageIDs = messageids
+
+	var ids []MessageID
+	if err := b.Request("forwardMessages", req, &ids); err != nil {
+		return nil, err
 	}
 
-	reqmsg := ReqEditMessage{ChatID: chatid, MessageID: messageid, SendMessage: *smsg}
-	err = b.Request("editMessageText", reqmsg, &msg)
-	return
+	return messageIDs(ids), nil
 }
 
-type ReqEditMessageMedia struct {
-	ChatID      int    `json:"chat_id"`
-	MessageID   int    `json:"message_id"`
-	Media       string `json:"media"`
-	ReplyMarkup string `json:"reply_markup,omitempty"`
+type ReqCopyMessage struct {
+	ChatID      int   `json:"chat_id"`
+	FromChatID  int   `json:"from_chat_id"`
+	MessageID   int   `json:"message_id,omitempty"`
+	MessageIDs  []int `json:"message_ids,omitempty"`
+	SendOptions `json:",omitempty,squash"`
 }
 
-func (b *Bot) EditMessageMedia(chatid, msgid int, file FormFile, caption string, keys ...*InlineKeyboardMarkup) (msg Message, err error) {
-	file.field = "photo"
+// CopyMessage send copy of the message without link to the original message
+func (b *Bot) CopyMessage(to, from, messageid int, opt ...SendOptions) (int, error) {
+	req := ReqCopyMessage{ChatID: to, FromChatID: from, MessageID: messageid}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	var id MessageID
+	err := b.Request("copyMessage", req, &id)
+	return id.MessageID, err
+}
 
-	payload := ReqEditMessageMedia{
-		ChatID:    chatid,
-		MessageID: msgid,
-		Media: InputMedia{
-			Caption: caption,
-			Type:    "photo",
-			Media:   "attach://" + file.field,
-		}.ToJSON(),
+// CopyMessages copy 1-100 messages, albums are grouped, service messages are skipped,
+// return identifiers of the sent messages
+func (b *Bot) CopyMessages(to, from int, messageids []int, opt ...SendOptions) ([]int, error) {
+	req := ReqCopyMessage{ChatID: to, FromChatID: from, MessageIDs: messageids}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
 	}
 
-	if len(keys) > 0 {
-		payload.ReplyMarkup = keys[0].ToJSON()
+	var ids []MessageID
+	if err := b.Request("copyMessages", req, &ids); err != nil {
+		return nil, err
 	}
 
-	err = b.FileRequest("editMessageMedia", file, payload, &msg)
-	return
+	return messageIDs(ids), nil
+}
+
+func messageIDs(ids []MessageID) []int {
+	msgids := make([]int, len(ids))
+	for i, id := range ids {
+		msgids[i] = id.MessageID
+	}
+
+	return msgids
 }
 
 //
@@ -353,34 +652,21 @@ func (b *Bot) EditMessageMedia(chatid, msgid int, file FormFile, caption string,
 //
 
 func (b *Bot) GetFile(fileid string) (f File, err error) {
-	resp, err := http.Get(b.addr + "getFile?file_id=" + fileid)
-	if err != nil {
-		return f, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		// TODO: read error from response body
-		return f, errors.New(resp.Status)
-	}
-
-	var respdata struct {
-		OK     bool
-		Result File
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&respdata)
-	return respdata.Result, err
+	err = b.Request("getFile", map[string]interface{}{"file_id": fileid}, &f)
+	return
 }
 
 func (b *Bot) DownloadFile(filepath string, w io.Writer) error {
-	resp, err := http.Get(b.fileaddr + filepath)
+	resp, err := b.req().
+		DisableAutoReadResponse().
+		SetContext(b.ctx).
+		Get(b.fileaddr + filepath)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
+	if resp.IsErrorState() {
 		return errors.New(resp.Status)
 	}
 
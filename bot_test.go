@@ -1,8 +1,8 @@
 package tebo
 
 import (
-	"fmt"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -11,7 +11,7 @@ import (
 
 var (
 	token    = ""
-	chatid   = ""
+	chatid   = 0
 	username = ""
 
 	bot *Bot
@@ -26,9 +26,9 @@ func init() {
 
 	token = os.Getenv("TEST_TOKEN")
 	username = os.Getenv("TEST_USERNAME")
-	chatid = os.Getenv("TEST_CHATID")
+	chatid, _ = strconv.Atoi(os.Getenv("TEST_CHATID"))
 
-	if token == "" || chatid == "" {
+	if token == "" || chatid == 0 {
 		log.Warning("SKIP tebo tests, env variable `TEST_TOKEN` or `TEST_CHATID` not specified")
 		return
 	}
@@ -44,7 +44,7 @@ func init() {
 }
 
 func TestReplyKeyboard(t *testing.T) {
-	if token == "" || chatid == "" {
+	if token == "" || chatid == 0 {
 		t.SkipNow()
 	}
 
@@ -70,13 +70,13 @@ func TestReplyKeyboard(t *testing.T) {
 		},
 	}
 
-	if _, err := bot.SendMessage(chatid, "keyboard", opt); err != nil {
+	if _, err := bot.SendMessage(chatid, NewMessage("keyboard", opt)); err != nil {
 		t.Error(err)
 	}
 }
 
 func TestInlineKeyboard(t *testing.T) {
-	if token == "" || chatid == "" {
+	if token == "" || chatid == 0 {
 		t.SkipNow()
 	}
 
@@ -97,15 +97,15 @@ func TestInlineKeyboard(t *testing.T) {
 		},
 	}
 
-	bot.UpdatesHandle(func(u *Update) bool {
-		if u.CallbackQuery != nil {
-			log.Notice("TestInlineKeyboard, callback data:", u.CallbackQuery.Data)
+	bot.UpdatesHandle(func(ctx *Context) bool {
+		if ctx.CallbackQuery != nil {
+			log.Notice("TestInlineKeyboard, callback data:", ctx.CallbackQuery.Data)
 			return false
 		}
 		return true
 	})
 
-	if err := bot.SendMessage(chatid, "keyboard", opt); err != nil {
+	if _, err := bot.SendMessage(chatid, NewMessage("keyboard", opt)); err != nil {
 		t.Error(err)
 	}
 }
@@ -114,8 +114,9 @@ func TestRouting(t *testing.T) {
 	if token == "" {
 		t.SkipNow()
 	}
-	bot.Handle("/start", func(m Message) string {
-		return fmt.Sprintf("Hello %s, ChatID: %d\nPassport: %+v", m.From.Username, m.Chat.ID, m.PassportData)
+	bot.Handle("/start", func(ctx *Context) *SendMessage {
+		m := ctx.Message
+		return ctx.NewTextMessage("Hello %s, ChatID: %d\nPassport: %+v", m.From.Username, m.Chat.ID, m.PassportData)
 	})
 
 	time.Sleep(30 * time.Second)
@@ -159,7 +160,7 @@ func TestSendMessage(t *testing.T) {
 		t.SkipNow()
 	}
 
-	msgid, err := bot.SendMessage(chatid, "Hello")
+	msgid, err := bot.SendMessage(chatid, NewMessage("Hello"))
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
@@ -179,7 +180,7 @@ func TestSendPhoto(t *testing.T) {
 		t.FailNow()
 	}
 
-	if err := bot.SendPhoto(chatid, f, "Image"); err != nil {
+	if _, err := bot.SendPhoto(chatid, FileFromReader("gopher.png", f), "Image"); err != nil {
 		t.Error(err)
 	}
 }
@@ -0,0 +1,242 @@
+package tebo
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// broadcastWorkers is number of messages of the broadcast sent concurrently,
+// actual rate is defined by the limiter
+const broadcastWorkers = 8
+
+// BroadcastReport describes delivery of the broadcast
+type BroadcastReport struct {
+	// Sent map chat id to id of the sent message
+	Sent map[int]int
+	// Failed map chat id to error of the last attempt
+	Failed map[int]error
+	// Canceled are chats the message is not sent to because the broadcast is canceled
+	Canceled []int
+}
+
+// ByClass group failed chats by class of the error: ErrBlocked, ErrChatNotFound,
+// ErrBadRequest, etc, network and other errors are grouped by nil key
+func (r *BroadcastReport) ByClass() map[error][]int {
+	classes := make(map[error][]int)
+	for id, err := range r.Failed {
+		class := ErrorClass(err)
+		classes[class] = append(classes[class], id)
+	}
+
+	return classes
+}
+
+// FailedBy return chats failed by error of the class
+func (r *BroadcastReport) FailedBy(class error) []int {
+	var ids []int
+	for id, err := range r.Failed {
+		if errors.Is(err, class) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// BroadcastJob is running broadcast, it can be paused, resumed and canceled
+type BroadcastJob struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	resume chan struct{}
+	report BroadcastReport
+	total  int
+
+	done chan struct{}
+}
+
+// Pause stop sending after messages which are being sent
+func (j *BroadcastJob) Pause() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.resume == nil {
+		j.resume = make(chan struct{})
+	}
+}
+
+func (j *BroadcastJob) Resume() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.resume != nil {
+		close(j.resume)
+		j.resume = nil
+	}
+}
+
+// Cancel stop the broadcast, remaining chats are reported as canceled
+func (j *BroadcastJob) Cancel() {
+	j.cancel()
+}
+
+// Done is closed when the broadcast is finished
+func (j *BroadcastJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait the end of the broadcast and return the report
+func (j *BroadcastJob) Wait() *BroadcastReport {
+	<-j.done
+	return &j.report
+}
+
+// Progress return number of sent and failed messages and total number of chats
+func (j *BroadcastJob) Progress() (sent, failed, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return len(j.report.Sent), len(j.report.Failed), j.total
+}
+
+// waitResume block while the broadcast is paused
+func (j *BroadcastJob) waitResume() error {
+	j.mu.Lock()
+	resume := j.resume
+	j.mu.Unlock()
+
+	if resume == nil {
+		return j.ctx.Err()
+	}
+
+	select {
+	case <-resume:
+		return j.ctx.Err()
+	case <-j.ctx.Done():
+		return j.ctx.Err()
+	}
+}
+
+func (j *BroadcastJob) result(chatid, msgid int, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case err == nil:
+		j.report.Sent[chatid] = msgid
+	case j.ctx.Err() != nil:
+		// retry interrupted by cancellation returns the last transient error
+		j.report.Canceled = append(j.report.Canceled, chatid)
+	default:
+		j.report.Failed[chatid] = err
+	}
+}
+
+// Broadcast send the message to known chats accepted by selector, nil selector
+// accepts all chats, chats which blocked the bot are skipped. Messages are sent
+// under rate limits, transient failures are repeated, chats which blocked
+// the bot are marked inactive. The broadcast is stopped if ctx is done.
+func (b *Bot) Broadcast(ctx context.Context, selector func(ChatInfo) bool, smsg *SendMessage) *BroadcastJob {
+	chats := b.Chats.List(func(info ChatInfo) bool {
+		return !info.Inactive && (selector == nil || selector(info))
+	})
+
+	j := &BroadcastJob{
+		report: BroadcastReport{
+			Sent:   make(map[int]int),
+			Failed: make(map[int]error),
+		},
+		total: len(chats),
+		done:  make(chan struct{}),
+	}
+	j.ctx, j.cancel = context.WithCancel(ctx)
+
+	// broadcast is stopped on shutdown of the bot
+	go func() {
+		select {
+		case <-b.ctx.Done():
+			j.cancel()
+		case <-j.ctx.Done():
+		}
+	}()
+
+	queue := make(chan int)
+	go func() {
+		defer close(queue)
+		for _, info := range chats {
+			select {
+			case queue <- info.ID:
+			case <-j.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < broadcastWorkers && i < len(chats); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chatid := range queue {
+				if err := j.waitResume(); err != nil {
+					j.result(chatid, 0, err)
+					continue
+				}
+
+				msgid, err := b.broadcastMessage(j.ctx, chatid, smsg)
+				j.result(chatid, msgid, err)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+
+		// chats not passed to workers
+		j.mu.Lock()
+		canceled := make(map[int]bool, len(j.report.Canceled))
+		for _, id := range j.report.Canceled {
+			canceled[id] = true
+		}
+		for _, info := range chats {
+			_, sent := j.report.Sent[info.ID]
+			_, failed := j.report.Failed[info.ID]
+			if !sent && !failed && !canceled[info.ID] {
+				j.report.Canceled = append(j.report.Canceled, info.ID)
+			}
+		}
+		j.mu.Unlock()
+
+		j.cancel()
+		close(j.done)
+	}()
+
+	return j
+}
+
+// broadcastMessage send the message to the chat, if retries are not enabled
+// for the bot then the default policy is used
+func (b *Bot) broadcastMessage(ctx context.Context, chatid int, smsg *SendMessage) (int, error) {
+	var msg Message
+	send := func() error {
+		return b.request(ctx, "sendMessage", ReqSendMessage{ChatID: chatid, SendMessage: *smsg}, &msg)
+	}
+
+	var err error
+	if b.retryPolicy == nil {
+		policy := DefaultRetryPolicy
+		err = policy.do(ctx, "sendMessage", send)
+	} else {
+		err = send()
+	}
+
+	if errors.Is(err, ErrBlocked) {
+		if info, ok := b.Chats.deactivate(chatid); ok {
+			b.saveChat(info)
+		}
+	}
+
+	return msg.MessageID, err
+}
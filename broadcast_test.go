@@ -0,0 +1,169 @@
+package tebo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func broadcastChats(t *testing.T, b *Bot, n int) {
+	var updates []Update
+	for i := 1; i <= n; i++ {
+		updates = append(updates, Update{UpdateID: i, Message: Message{
+			Chat: Chat{ID: i, Type: "private", FirstName: "user"},
+			Date: time.Now().Unix(),
+		}})
+	}
+
+	if err := b.updateHistory(updates); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	api := newFakeAPI(t)
+
+	var mu sync.Mutex
+	attempts := make(map[int]int)
+	api.Handle("sendMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		var req ReqSendMessage
+		decodeRequest(r, &req)
+
+		mu.Lock()
+		attempts[req.ChatID]++
+		n := attempts[req.ChatID]
+		mu.Unlock()
+
+		switch {
+		case req.ChatID == 2:
+			return nil, &ErrorResponse{ErrorCode: 403, Description: "Forbidden: bot was blocked by the user"}
+		case req.ChatID == 3 && n == 1:
+			return nil, &ErrorResponse{ErrorCode: 502, Description: "Bad Gateway"}
+		case req.ChatID == 4:
+			return nil, &ErrorResponse{ErrorCode: 400, Description: "Bad Request: chat not found"}
+		}
+		return Message{MessageID: 100 + req.ChatID, Chat: Chat{ID: req.ChatID}}, nil
+	})
+
+	b := newTestBot(t, api, WithRetry(RetryPolicy{MinBackoff: time.Millisecond}))
+	broadcastChats(t, b, 5)
+
+	report := b.Broadcast(context.Background(), ChatInfo.IsPrivate, NewMessage("news")).Wait()
+
+	if len(report.Sent) != 3 || report.Sent[3] != 103 || report.Sent[5] != 105 {
+		t.Errorf("unexpected sent messages %v", report.Sent)
+	}
+
+	classes := report.ByClass()
+	if ids := classes[ErrBlocked]; len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("unexpected blocked chats %v", ids)
+	}
+	if ids := classes[ErrChatNotFound]; len(ids) != 1 || ids[0] != 4 {
+		t.Errorf("unexpected not found chats %v", ids)
+	}
+
+	if info, _ := b.Chats.Info(2); !info.Inactive {
+		t.Error("blocked chat is not marked inactive")
+	}
+
+	// inactive chat is skipped
+	report = b.Broadcast(context.Background(), nil, NewMessage("news")).Wait()
+	if _, ok := report.Failed[2]; ok || len(report.Sent) != 3 {
+		t.Errorf("unexpected report of the second broadcast %+v", report)
+	}
+}
+
+func TestBroadcastPauseCancel(t *testing.T) {
+	api := newFakeAPI(t)
+
+	var job *BroadcastJob
+	started := make(chan struct{})
+	var once sync.Once
+
+	api.Handle("sendMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		once.Do(func() {
+			<-started
+			job.Pause()
+		})
+		return Message{MessageID: 1}, nil
+	})
+
+	b := newTestBot(t, api)
+	broadcastChats(t, b, 20)
+
+	job = b.Broadcast(context.Background(), nil, NewMessage("news"))
+	close(started)
+
+	time.Sleep(50 * time.Millisecond)
+	sent, _, total := job.Progress()
+	if total != 20 || sent > broadcastWorkers {
+		t.Fatalf("broadcast is not paused, sent %d of %d", sent, total)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n, _, _ := job.Progress(); n != sent {
+		t.Errorf("messages are sent while paused: %d, %d", sent, n)
+	}
+
+	job.Cancel()
+	report := job.Wait()
+
+	sort.Ints(report.Canceled)
+	if len(report.Sent)+len(report.Canceled) != 20 || len(report.Canceled) < 20-broadcastWorkers {
+		t.Errorf("unexpected report %d sent, %d canceled", len(report.Sent), len(report.Canceled))
+	}
+
+	if !errors.Is(job.ctx.Err(), context.Canceled) {
+		t.Error("job is not canceled")
+	}
+}
+
+func TestBroadcastResume(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Handle("sendMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		return Message{MessageID: 1}, nil
+	})
+
+	b := newTestBot(t, api)
+	broadcastChats(t, b, 10)
+
+	job := b.Broadcast(context.Background(), nil, NewMessage("news"))
+	job.Pause()
+	job.Resume()
+
+	if report := job.Wait(); len(report.Sent) != 10 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestBroadcastCancelRetry(t *testing.T) {
+	api := newFakeAPI(t)
+
+	requested := make(chan struct{}, 3)
+	api.Handle("sendMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		requested <- struct{}{}
+		return nil, &ErrorResponse{
+			ErrorCode:   429,
+			Description: "Too Many Requests: retry after 30",
+			Parameters:  &ResponseParameters{RetryAfter: 30},
+		}
+	})
+
+	b := newTestBot(t, api)
+	broadcastChats(t, b, 3)
+
+	job := b.Broadcast(context.Background(), nil, NewMessage("news"))
+	for i := 0; i < 3; i++ {
+		<-requested
+	}
+	job.Cancel()
+
+	report := job.Wait()
+	if len(report.Failed) != 0 || len(report.Canceled) != 3 {
+		t.Errorf("chats waiting for retry are not canceled: %+v", report)
+	}
+}
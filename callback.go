@@ -0,0 +1,48 @@
+package tebo
+
+type ReqAnswerCallbackQuery struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+	ShowAlert       bool   `json:"show_alert,omitempty"`
+	URL             string `json:"url,omitempty"`
+	CacheTime       int    `json:"cache_time,omitempty"`
+}
+
+// AnswerCallbackQuery send answer to the callback query, until it is answered
+// telegram client shows progress bar on the pressed button
+func (b *Bot) AnswerCallbackQuery(answer ReqAnswerCallbackQuery) error {
+	var resp bool
+	return b.Request("answerCallbackQuery", answer, &resp)
+}
+
+// AnswerCallback answer the callback query of the current update,
+// callback queries not answered by handlers are answered after routing
+func (ctx *Context) AnswerCallback(answer ReqAnswerCallbackQuery) error {
+	if ctx.CallbackQuery == nil {
+		return nil
+	}
+
+	if ctx.answered.Swap(true) {
+		return nil
+	}
+
+	answer.CallbackQueryID = ctx.CallbackQuery.ID
+	return ctx.Bot.AnswerCallbackQuery(answer)
+}
+
+// Answer the callback query with notification shown at the top of the chat
+func (ctx *Context) Answer(text string) error {
+	return ctx.AnswerCallback(ReqAnswerCallbackQuery{Text: text})
+}
+
+// Alert answer the callback query with alert which should be closed by user
+func (ctx *Context) Alert(text string) error {
+	return ctx.AnswerCallback(ReqAnswerCallbackQuery{Text: text, ShowAlert: true})
+}
+
+// acknowledge the callback query if it is not answered by handler
+func (ctx *Context) acknowledge() {
+	if err := ctx.AnswerCallback(ReqAnswerCallbackQuery{}); err != nil {
+		log.Warningf("failed to answer callback query: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package tebo
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestAnswerCallbackQuery(t *testing.T) {
+	api := newFakeAPI(t)
+
+	var mu sync.Mutex
+	var answers []ReqAnswerCallbackQuery
+	api.Handle("answerCallbackQuery", func(r *http.Request) (interface{}, *ErrorResponse) {
+		var req ReqAnswerCallbackQuery
+		decodeRequest(r, &req)
+		mu.Lock()
+		answers = append(answers, req)
+		mu.Unlock()
+		return true, nil
+	})
+
+	b := newTestBot(t, api)
+	b.UpdatesHandle(func(ctx *Context) bool {
+		if ctx.CallbackQuery.Data == "alert" {
+			ctx.Alert("done")
+			ctx.Answer("twice")
+		}
+		return true
+	})
+
+	callback := func(id, data string) Update {
+		return Update{CallbackQuery: &CallbackQuery{
+			ID:      id,
+			From:    User{ID: 10},
+			Message: Message{MessageID: 1, Chat: Chat{ID: 10}},
+			Data:    data,
+		}}
+	}
+
+	b.route(callback("1", "alert"))
+	b.route(callback("2", "silent"))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(answers) != 2 {
+		t.Fatalf("expected 2 answers, got %+v", answers)
+	}
+
+	if a := answers[0]; a.CallbackQueryID != "1" || a.Text != "done" || !a.ShowAlert {
+		t.Errorf("unexpected explicit answer %+v", a)
+	}
+
+	if a := answers[1]; a.CallbackQueryID != "2" || a.Text != "" || a.ShowAlert {
+		t.Errorf("unexpected automatic answer %+v", a)
+	}
+}
+
+func TestAnswerCallbackQueryExpectAnswer(t *testing.T) {
+	api := newFakeAPI(t)
+
+	answers := make(chan ReqAnswerCallbackQuery, 2)
+	api.Handle("answerCallbackQuery", func(r *http.Request) (interface{}, *ErrorResponse) {
+		var req ReqAnswerCallbackQuery
+		decodeRequest(r, &req)
+		answers <- req
+		return true, nil
+	})
+
+	b := newTestBot(t, api)
+
+	b.Handle("/ask", func(ctx *Context) *SendMessage {
+		actx, ok := ctx.ExpectAnswer()
+		if ok {
+			actx.Alert("saved")
+		}
+		return nil
+	})
+
+	b.dispatcher.dispatch(textUpdate(1, 10, "/ask"))
+	b.dispatcher.dispatch(Update{UpdateID: 2, CallbackQuery: &CallbackQuery{
+		ID:      "1",
+		From:    User{ID: 10},
+		Message: Message{MessageID: 1, Chat: Chat{ID: 10}},
+		Data:    "yes",
+	}})
+	b.dispatcher.wait()
+
+	close(answers)
+
+	var got []ReqAnswerCallbackQuery
+	for a := range answers {
+		got = append(got, a)
+	}
+
+	if len(got) != 1 || got[0].Text != "saved" || !got[0].ShowAlert {
+		t.Errorf("unexpected answers %+v", got)
+	}
+}
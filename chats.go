@@ -1,26 +1,108 @@
 package tebo
 
 import (
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ChatInfo is known information about the chat
+type ChatInfo struct {
+	ID        int    `msgpack:"id"`
+	Type      string `msgpack:"type"`
+	Title     string `msgpack:"title,omitempty"`
+	Username  string `msgpack:"username,omitempty"`
+	FirstName string `msgpack:"first_name,omitempty"`
+	LastName  string `msgpack:"last_name,omitempty"`
+
+	// Language of the user in the private chat
+	Language string `msgpack:"language,omitempty"`
+
+	FirstSeen time.Time `msgpack:"first_seen"`
+	LastSeen  time.Time `msgpack:"last_seen"`
+
+	// Status of the bot in the chat from my_chat_member update
+	Status string `msgpack:"status,omitempty"`
+	// Inactive is set if the bot is blocked by the user or removed from the chat
+	Inactive bool `msgpack:"inactive,omitempty"`
+}
+
+// lastSeenPrecision limit saving of the chat info on each message
+const lastSeenPrecision = time.Minute
+
+// Name return username of the chat or title if chat has no username
+func (ci ChatInfo) Name() string {
+	if ci.Username != "" {
+		return ci.Username
+	}
+
+	return ci.Title
+}
+
+func (ci ChatInfo) IsPrivate() bool {
+	return ci.Type == "private"
+}
+
+func (ci ChatInfo) IsGroup() bool {
+	return ci.Type == "group" || ci.Type == "supergroup"
+}
+
+// differs return true if info should be saved
+func (ci ChatInfo) differs(old ChatInfo) bool {
+	if ci.LastSeen.Sub(old.LastSeen) >= lastSeenPrecision {
+		return true
+	}
+
+	ci.LastSeen, old.LastSeen = time.Time{}, time.Time{}
+	ci.FirstSeen, old.FirstSeen = ci.FirstSeen.UTC(), old.FirstSeen.UTC()
+
+	return ci != old
+}
+
 type chat struct {
-	ID       int
-	Username string
+	ID int
+
+	infoMu sync.Mutex
+	info   ChatInfo
+
+	// mu guard fields below, handler detached in ExpectAnswer
+	// runs concurrently with following updates of the chat
+	mu     sync.Mutex
+	expect chan *Context
 
 	lastMessageIsBot bool
 	editMessageID    int
 
-	expectContext chan *Context
-	expectCancel  chan bool
-
 	fsm *FSM
 }
 
+// Info return known information about the chat
+func (c *chat) Info() ChatInfo {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+
+	return c.info
+}
+
 type chats struct {
 	chats sync.Map
 	names sync.Map
+
+	// migrated map ids of groups upgraded to supergroups to the new ids
+	migrated sync.Map
+}
+
+func newChatInfo(tc Chat) ChatInfo {
+	return ChatInfo{
+		ID:        tc.ID,
+		Type:      tc.Type,
+		Title:     tc.Title,
+		Username:  tc.Username,
+		FirstName: tc.FirstName,
+		LastName:  tc.LastName,
+	}
 }
 
 func (c *chats) Get(tc Chat) *chat {
@@ -31,20 +113,150 @@ func (c *chats) Get(tc Chat) *chat {
 		return ch.(*chat)
 	}
 
-	username := tc.Username
-	if tc.Username == "" && tc.Title != "" {
-		username = tc.Title
+	return c.store(newChatInfo(tc))
+}
+
+// store add chat with the info or replace info of the known chat
+func (c *chats) store(info ChatInfo) *chat {
+	val, _ := c.chats.LoadOrStore(info.ID, &chat{ID: info.ID})
+	ch := val.(*chat)
+
+	ch.infoMu.Lock()
+	defer ch.infoMu.Unlock()
+
+	c.reindex(ch, ch.info, info)
+	ch.info = info
+
+	return ch
+}
+
+// reindex replace names of the chat after rename,
+// chat can be found by username and by title
+func (c *chats) reindex(ch *chat, old, info ChatInfo) {
+	for _, name := range []string{old.Username, old.Title} {
+		if name != "" && name != info.Username && name != info.Title {
+			c.names.CompareAndDelete(name, ch)
+		}
 	}
 
-	ch := &chat{
-		ID:       tc.ID,
-		Username: username,
+	for _, name := range []string{info.Username, info.Title} {
+		if name != "" {
+			c.names.Store(name, ch)
+		}
 	}
+}
 
-	c.chats.Store(tc.ID, ch)
-	c.names.Store(tc.Username, ch)
+// update apply the update to the info of the chat,
+// return the info and true if it is changed and should be saved
+func (c *chats) update(u Update) (ChatInfo, bool) {
+	tc := updateChat(u)
+	if tc.ID == 0 {
+		return ChatInfo{}, false
+	}
 
-	return ch
+	ch := c.Get(tc)
+
+	ch.infoMu.Lock()
+	defer ch.infoMu.Unlock()
+
+	// chat of the update is complete if it has a name, it replaces the old
+	// names, so removed username or changed title are applied
+	info := ch.info
+	if tc.Type != "" && (tc.Title != "" || tc.Username != "" || tc.FirstName != "") {
+		info.Type = tc.Type
+		info.Title = tc.Title
+		info.Username = tc.Username
+		info.FirstName = tc.FirstName
+		info.LastName = tc.LastName
+	}
+
+	if from := updateFrom(u); info.IsPrivate() && from.LanguageCode != "" {
+		info.Language = from.LanguageCode
+	}
+
+	if date := updateDate(u); date > 0 {
+		t := time.Unix(date, 0)
+		if info.FirstSeen.IsZero() || t.Before(info.FirstSeen) {
+			info.FirstSeen = t
+		}
+		if t.After(info.LastSeen) {
+			info.LastSeen = t
+		}
+	}
+
+	// private chat is kicked if the user blocked the bot
+	if u.MyChatMember != nil {
+		info.Status = u.MyChatMember.NewChatMember.Status
+		info.Inactive = info.Status == "kicked" || info.Status == "left"
+	}
+
+	old := ch.info
+	c.reindex(ch, old, info)
+	ch.info = info
+
+	return info, info.differs(old)
+}
+
+// deactivate mark the chat which blocked the bot,
+// return false if the chat is unknown or already inactive
+func (c *chats) deactivate(id int) (ChatInfo, bool) {
+	val, ok := c.chats.Load(id)
+	if !ok {
+		return ChatInfo{}, false
+	}
+	ch := val.(*chat)
+
+	ch.infoMu.Lock()
+	defer ch.infoMu.Unlock()
+
+	if ch.info.Inactive {
+		return ch.info, false
+	}
+	ch.info.Inactive = true
+
+	return ch.info, true
+}
+
+// migrate move chat of the group to the new id of the supergroup,
+// return false if the group is already migrated
+func (c *chats) migrate(from, to int) bool {
+	if id, ok := c.migrated.Load(from); ok && id.(int) == to {
+		return false
+	}
+	c.migrated.Store(from, to)
+
+	val, ok := c.chats.LoadAndDelete(from)
+	if !ok {
+		return true
+	}
+	old := val.(*chat)
+
+	info := old.Info()
+	info.ID, info.Type = to, "supergroup"
+
+	ch, _ := c.chats.LoadOrStore(to, &chat{ID: to, info: info})
+
+	c.names.Range(func(name, val interface{}) bool {
+		if val.(*chat) == old {
+			c.names.Store(name, ch)
+		}
+		return true
+	})
+
+	// handler waiting for answer in the group will never get it
+	old.answer(nil)
+
+	return true
+}
+
+// Migrated return new id of the group upgraded to a supergroup
+func (c *chats) Migrated(id int) (int, bool) {
+	to, ok := c.migrated.Load(id)
+	if !ok {
+		return 0, false
+	}
+
+	return to.(int), true
 }
 
 func (c *chats) LookupByName(name string) (*chat, bool) {
@@ -61,37 +273,129 @@ func (c *chats) LookupByName(name string) (*chat, bool) {
 func (c *chats) Range(f func(id int, username string) bool) {
 	c.chats.Range(func(_, val interface{}) bool {
 		ch := val.(*chat)
-		return f(ch.ID, ch.Username)
+		return f(ch.ID, ch.Info().Name())
+	})
+}
+
+// Info return information about the chat
+func (c *chats) Info(id int) (ChatInfo, bool) {
+	ch, ok := c.chats.Load(id)
+	if !ok {
+		return ChatInfo{}, false
+	}
+
+	return ch.(*chat).Info(), true
+}
+
+// List return info of chats accepted by filter sorted by id, nil filter accepts all chats
+func (c *chats) List(filter func(ChatInfo) bool) []ChatInfo {
+	var list []ChatInfo
+	c.chats.Range(func(_, val interface{}) bool {
+		if info := val.(*chat).Info(); filter == nil || filter(info) {
+			list = append(list, info)
+		}
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list
+}
+
+// Private return private chats with users
+func (c *chats) Private() []ChatInfo {
+	return c.List(ChatInfo.IsPrivate)
+}
+
+// Groups return groups and supergroups
+func (c *chats) Groups() []ChatInfo {
+	return c.List(ChatInfo.IsGroup)
+}
+
+// Active return chats seen since the time, which did not block or remove the bot
+func (c *chats) Active(since time.Time) []ChatInfo {
+	return c.List(func(info ChatInfo) bool {
+		return !info.Inactive && !info.LastSeen.Before(since)
 	})
 }
 
 // ExpectAnswer wait next message, intercept it if this message not a command
 // return false if next message is command
 func (c *chat) ExpectAnswer() (ctx *Context, ok bool) {
+	return c.waitAnswer(c.expectAnswer(nil))
+}
+
+// expectAnswer register channel for the next message of the chat, return nil
+// if the bot is closed, it is checked under the lock, so Shutdown cancelling
+// waiting handlers either sees the channel or it is not registered
+func (c *chat) expectAnswer(closed *atomic.Bool) chan *Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if closed != nil && closed.Load() {
+		return nil
+	}
+
 	c.lastMessageIsBot = false
+	c.expect = make(chan *Context, 1)
 
-	c.expectContext = make(chan *Context)
-	c.expectCancel = make(chan bool)
+	return c.expect
+}
 
-	select {
-	case ctx = <-c.expectContext:
-		ok = true
-	case <-c.expectCancel:
-		ok = false
+func (c *chat) waitAnswer(expect chan *Context) (*Context, bool) {
+	if expect == nil {
+		return nil, false
 	}
 
-	return
+	ctx := <-expect
+	return ctx, ctx != nil
 }
 
-func (c *chat) closeExpectChannels() {
-	close(c.expectContext)
-	close(c.expectCancel)
+// answer pass context to the handler waiting in ExpectAnswer,
+// nil context cancel the waiting, return false if nobody waits
+func (c *chat) answer(ctx *Context) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expect == nil {
+		return false
+	}
+
+	c.expect <- ctx
+	c.expect = nil
 
-	c.expectContext = nil
-	c.expectCancel = nil
+	return true
 }
 
 func (c *chat) setEditMessageID(msgid int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.editMessageID = msgid
 	c.lastMessageIsBot = true
 }
+
+// editMessage return id of the message to edit,
+// false if the last message of the chat is not sent by bot
+func (c *chat) editMessage() (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.editMessageID, c.lastMessageIsBot
+}
+
+func (c *chat) getFSM() *FSM {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.fsm
+}
+
+func (c *chat) setFSM(fsm *FSM) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fsm = fsm
+}
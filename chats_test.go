@@ -0,0 +1,92 @@
+package tebo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChatInfo(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+	now := time.Now().Unix()
+
+	b := newBot(testToken)
+	if err := b.readHistory(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	err := b.updateHistory([]Update{
+		{UpdateID: 1, Message: Message{
+			Chat: Chat{ID: 1, Type: "private", FirstName: "Ann", Username: "ann"},
+			From: User{ID: 1, LanguageCode: "en"},
+			Date: now - 3600,
+		}},
+		{UpdateID: 2, Message: Message{
+			Chat: Chat{ID: -2, Type: "supergroup", Title: "Team"},
+			Date: now - 7200,
+		}},
+		{UpdateID: 3, Message: Message{
+			Chat: Chat{ID: -2, Type: "supergroup", Title: "New Team"},
+			Date: now - 60,
+		}},
+		{UpdateID: 4, MyChatMember: &ChatMemberUpdated{
+			Chat:          Chat{ID: 3, Type: "private", FirstName: "Bob"},
+			Date:          now,
+			NewChatMember: ChatMember{Status: "kicked"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// group is found by the title, old title is forgotten
+	if id, ok := b.LookupChatID("New Team"); !ok || id != -2 {
+		t.Errorf("group is not found by title, id %d", id)
+	}
+	if _, ok := b.LookupChatID("Team"); ok {
+		t.Error("old title of the group is still indexed")
+	}
+
+	info, _ := b.Chats.Info(-2)
+	if info.FirstSeen.Unix() != now-7200 || info.LastSeen.Unix() != now-60 {
+		t.Errorf("unexpected dates of the group %v %v", info.FirstSeen, info.LastSeen)
+	}
+
+	if private := b.Chats.Private(); len(private) != 2 {
+		t.Errorf("unexpected private chats %+v", private)
+	}
+	if groups := b.Chats.Groups(); len(groups) != 1 || groups[0].ID != -2 {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+	if active := b.Chats.Active(time.Unix(now-3600, 0)); len(active) != 2 || active[0].ID != -2 || active[1].ID != 1 {
+		t.Errorf("unexpected active chats %+v", active)
+	}
+
+	b.Close()
+
+	// chats are restored without history
+	for _, name := range []string{filename, filename + ".snapshot"} {
+		os.Remove(name)
+	}
+
+	b = newBot(testToken)
+	if err := b.readHistory(filename); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	info, ok := b.Chats.Info(1)
+	if !ok || info.Language != "en" || info.Name() != "ann" {
+		t.Errorf("private chat is not restored: %+v", info)
+	}
+
+	info, ok = b.Chats.Info(3)
+	if !ok || !info.Inactive || info.Status != "kicked" {
+		t.Errorf("blocked chat is not restored: %+v", info)
+	}
+
+	if id, ok := b.LookupChatID("New Team"); !ok || id != -2 {
+		t.Errorf("group is not restored, id %d", id)
+	}
+}
@@ -0,0 +1,144 @@
+package tebo
+
+import (
+	"sync"
+)
+
+const chatsMagic = "TEBOCHT"
+
+// ChatStore keep information about chats independently of the history,
+// so it is not lost when old updates are removed
+type ChatStore interface {
+	// Range pass saved chats until f returns false
+	Range(f func(ChatInfo) bool) error
+
+	Save(info ChatInfo) error
+
+	Close() error
+}
+
+// WithChatStore set storage of chats, by default chats are saved
+// to the file next to the history file with .chats suffix
+func WithChatStore(store ChatStore) Option {
+	return func(b *Bot) {
+		b.chatStore = store
+	}
+}
+
+// FileChats keep information about chats in the file,
+// changes are appended and the file is compacted on open
+type FileChats struct {
+	mu    sync.Mutex
+	log   *recordLog
+	chats map[int]ChatInfo
+}
+
+// NewFileChats open file of chats, records are encrypted if keys are passed
+func NewFileChats(filename string, keys ...KeyProvider) (*FileChats, error) {
+	s := &FileChats{
+		log:   newRecordLog(filename, chatsMagic, keys),
+		chats: make(map[int]ChatInfo),
+	}
+
+	err := s.log.read(func() interface{} { return new(ChatInfo) }, func(v interface{}) {
+		info := v.(*ChatInfo)
+		s.chats[info.ID] = *info
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileChats) compact() error {
+	return s.log.compact(func(write func(v interface{}) error) error {
+		for _, info := range s.chats {
+			if err := write(info); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *FileChats) Range(f func(ChatInfo) bool) error {
+	s.mu.Lock()
+	list := make([]ChatInfo, 0, len(s.chats))
+	for _, info := range s.chats {
+		list = append(list, info)
+	}
+	s.mu.Unlock()
+
+	for _, info := range list {
+		if !f(info) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *FileChats) Save(info ChatInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chats[info.ID] = info
+
+	compact, err := s.log.append(info, len(s.chats))
+	if err != nil || !compact {
+		return err
+	}
+
+	return s.compact()
+}
+
+// Close compact and close the file
+func (s *FileChats) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log.file == nil {
+		return nil
+	}
+
+	err := s.compact()
+	if e := s.log.close(); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+// saveChat store changed information about the chat
+func (b *Bot) saveChat(info ChatInfo) {
+	b.chatStoreMu.Lock()
+	defer b.chatStoreMu.Unlock()
+
+	if b.chatStore == nil {
+		return
+	}
+
+	if err := b.chatStore.Save(info); err != nil {
+		log.Errorf("failed to save chat %d: %v", info.ID, err)
+	}
+}
+
+// closeChats close the store of chats
+func (b *Bot) closeChats() error {
+	b.chatStoreMu.Lock()
+	defer b.chatStoreMu.Unlock()
+
+	if b.chatStore == nil {
+		return nil
+	}
+
+	err := b.chatStore.Close()
+	b.chatStore = nil
+
+	return err
+}
@@ -1,19 +1,46 @@
 package tebo
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // Context argument for handlers
 type Context struct {
 	Bot *Bot
 
-	Update
-	Message
+	Update  `json:"update"`
+	Message `json:"message"`
+
+	// MediaGroup contains all messages of the album, if aggregation is enabled
+	MediaGroup []Message `json:"media_group,omitempty"`
+
+	// Poll is the state of the poll update or the poll of the message,
+	// it resolves ambiguity of Update.Poll and Message.Poll
+	Poll *Poll `json:"-"`
 
 	chat *chat
 
+	// detach let the following updates of the chat to be processed
+	// while handler is waiting for an answer
+	detach func()
+
+	// answered is set if callback query is answered
+	answered atomic.Bool
+
+	// answers are contexts received by ExpectAnswer, they are finished
+	// together with this context, guarded by answersMu
+	answers   []*Context
+	finished  bool
+	answersMu sync.Mutex
+
+	// sessions used by the handler, saved after routing
+	sessions         []*Session
+	sessionsReleased bool
+	sessionsMu       sync.Mutex
+
 	sync.Map
 }
 
@@ -21,19 +48,32 @@ func (b *Bot) newContext(u Update) *Context {
 	ctx := &Context{
 		Bot:     b,
 		Update:  u,
-		Message: u.Message,
+		Message: updateMessage(u),
 	}
 
-	if u.CallbackQuery != nil {
-		ctx.Message = u.CallbackQuery.Message
+	ctx.Poll = u.Poll
+	if ctx.Poll == nil {
+		ctx.Poll = ctx.Message.Poll
 	}
 
-	ctx.chat = b.Chats.Get(ctx.Message.Chat)
+	// updates without message still have chat and user to respond
+	if ctx.Message.Chat.ID == 0 {
+		ctx.Message.Chat = updateChat(u)
+	}
+	if ctx.Message.From.ID == 0 {
+		ctx.Message.From = updateFrom(u)
+	}
+
+	if ctx.Message.Chat.ID != 0 {
+		ctx.chat = b.Chats.Get(ctx.Message.Chat)
+	} else {
+		ctx.chat = new(chat)
+	}
 
 	if u.CallbackQuery != nil {
 		if u.CallbackQuery.Data != "" {
 			if fsm, ok := b.lookupFSM(u.CallbackQuery.Data); ok {
-				ctx.chat.fsm = fsm.root
+				ctx.chat.setFSM(fsm.root)
 			}
 		}
 
@@ -67,9 +107,55 @@ func (ctx *Context) Send(smsg *SendMessage) (int, error) {
 	return msgid, err
 }
 
+// messageRef return reference to the message of the current chat, or to the
+// inline message if the update is callback query from it
+func (ctx *Context) messageRef(messageid int) MessageRef {
+	if ctx.CallbackQuery != nil && ctx.CallbackQuery.InlineMessageID != "" {
+		return InlineMessage(ctx.CallbackQuery.InlineMessageID)
+	}
+
+	return ChatMessage(ctx.Chat.ID, messageid)
+}
+
+// isInline return true if the update is callback query from the inline message,
+// it can not be answered by new message
+func (ctx *Context) isInline() bool {
+	return ctx.CallbackQuery != nil && ctx.CallbackQuery.InlineMessageID != ""
+}
+
 // Edit message of the current chat
 func (ctx *Context) Edit(messageid int, smsg *SendMessage) error {
-	_, err := ctx.Bot.EditMessage(ctx.Chat.ID, messageid, smsg)
+	_, err := ctx.Bot.EditMessageText(ctx.messageRef(messageid), smsg)
+	return err
+}
+
+// EditCaption of the media message of the current chat
+func (ctx *Context) EditCaption(messageid int, caption string, opt ...SendOptions) error {
+	_, err := ctx.Bot.EditMessageCaption(ctx.messageRef(messageid), caption, opt...)
+	return err
+}
+
+// EditReplyMarkup change inline keyboard of the message of the current chat
+func (ctx *Context) EditReplyMarkup(messageid int, markup *InlineKeyboardMarkup) error {
+	_, err := ctx.Bot.EditMessageReplyMarkup(ctx.messageRef(messageid), markup)
+	return err
+}
+
+// EditMedia replace media of the message of the current chat
+func (ctx *Context) EditMedia(messageid int, media InputMedia, markup ...*InlineKeyboardMarkup) error {
+	_, err := ctx.Bot.EditMessageMedia(ctx.messageRef(messageid), media, markup...)
+	return err
+}
+
+// EditLiveLocation move live location of the message of the current chat
+func (ctx *Context) EditLiveLocation(messageid int, loc Location, markup ...*InlineKeyboardMarkup) error {
+	_, err := ctx.Bot.EditMessageLiveLocation(ctx.messageRef(messageid), loc, markup...)
+	return err
+}
+
+// StopLiveLocation stop updating of the live location of the current chat
+func (ctx *Context) StopLiveLocation(messageid int, markup ...*InlineKeyboardMarkup) error {
+	_, err := ctx.Bot.StopMessageLiveLocation(ctx.messageRef(messageid), markup...)
 	return err
 }
 
@@ -84,13 +170,102 @@ func (ctx *Context) SendTextMessage(text string, a ...interface{}) (int, error)
 }
 
 func (ctx *Context) EditMessage(messageid int, text string, opt ...SendOptions) error {
-	_, err := ctx.Bot.EditMessage(ctx.Chat.ID, messageid, ctx.NewMessage(text, opt...))
-	return err
+	return ctx.Edit(messageid, ctx.NewMessage(text, opt...))
+}
+
+// currentMessages return identifiers of the current message or all messages of the album
+func (ctx *Context) currentMessages() []int {
+	if len(ctx.MediaGroup) == 0 {
+		return []int{ctx.MessageID}
+	}
+
+	ids := make([]int, len(ctx.MediaGroup))
+	for i, msg := range ctx.MediaGroup {
+		ids[i] = msg.MessageID
+	}
+
+	return ids
+}
+
+// Forward the messages of the current chat to another chat, by default
+// it is the current message, return identifiers of the forwarded messages
+func (ctx *Context) Forward(to int, messageids ...int) ([]int, error) {
+	if len(messageids) == 0 {
+		messageids = ctx.currentMessages()
+	}
+
+	return ctx.Bot.ForwardMessages(to, ctx.Chat.ID, messageids)
+}
+
+// CopyTo send copy of the messages of the current chat to another chat, by default
+// it is the current message, return identifiers of the copied messages
+func (ctx *Context) CopyTo(to int, messageids ...int) ([]int, error) {
+	if len(messageids) == 0 {
+		messageids = ctx.currentMessages()
+	}
+
+	return ctx.Bot.CopyMessages(to, ctx.Chat.ID, messageids)
 }
 
-// Expect answer of this user
+// Expect answer of this user, while waiting the following updates of the chat
+// are processed by other workers, so the rest of the handler runs concurrently
+// with them and their order relative to it is not guaranteed
 func (ctx *Context) ExpectAnswer() (*Context, bool) {
-	return ctx.chat.ExpectAnswer()
+	// update without chat, such as inline query, can not be answered
+	if ctx.chat.ID == 0 {
+		return nil, false
+	}
+
+	expect := ctx.chat.expectAnswer(&ctx.Bot.closed)
+	if expect == nil {
+		return nil, false
+	}
+
+	if ctx.detach != nil {
+		ctx.detach()
+	}
+
+	actx, ok := ctx.chat.waitAnswer(expect)
+	if ok {
+		ctx.adopt(actx)
+	}
+
+	return actx, ok
+}
+
+// adopt the answer context, so it is finished after the handler waiting for it,
+// if the handler is already finished the answer is finished at once
+func (ctx *Context) adopt(actx *Context) {
+	ctx.answersMu.Lock()
+	if !ctx.finished {
+		ctx.answers = append(ctx.answers, actx)
+		ctx.answersMu.Unlock()
+		return
+	}
+	ctx.answersMu.Unlock()
+
+	actx.finish()
+}
+
+// finish the context after routing: callback query not answered by handler
+// is acknowledged and sessions are saved and released,
+// answer contexts received by the handler are finished too
+func (ctx *Context) finish() {
+	ctx.answersMu.Lock()
+	ctx.finished = true
+	answers := ctx.answers
+	ctx.answers = nil
+	ctx.answersMu.Unlock()
+
+	for _, actx := range answers {
+		actx.finish()
+	}
+
+	if ctx.CallbackQuery != nil {
+		ctx.acknowledge()
+	}
+
+	ctx.releaseSessions()
 }
 
 func (ctx *Context) NewMessage(text string, opt ...SendOptions) *SendMessage {
@@ -102,9 +277,9 @@ func (ctx *Context) NewTextMessage(text string, a ...interface{}) *SendMessage {
 }
 
 func (ctx *Context) EditOrSendMessage(text string, opt ...SendOptions) (int, error) {
-	if ctx.chat.lastMessageIsBot {
-		err := ctx.EditMessage(ctx.chat.editMessageID, text, opt...)
-		return ctx.chat.editMessageID, err
+	if msgid, ok := ctx.chat.editMessage(); ok || ctx.isInline() {
+		err := ctx.EditMessage(msgid, text, opt...)
+		return msgid, ignoreNotModified(err)
 	}
 
 	msgid, err := ctx.SendMessage(text, opt...)
@@ -118,9 +293,9 @@ func (ctx *Context) EditOrSendMessage(text string, opt ...SendOptions) (int, err
 }
 
 func (ctx *Context) EditOrSend(smsg *SendMessage) (int, error) {
-	if ctx.chat.lastMessageIsBot {
-		err := ctx.Edit(ctx.chat.editMessageID, smsg)
-		return ctx.chat.editMessageID, err
+	if msgid, ok := ctx.chat.editMessage(); ok || ctx.isInline() {
+		err := ctx.Edit(msgid, smsg)
+		return msgid, ignoreNotModified(err)
 	}
 
 	msgid, err := ctx.Send(smsg)
@@ -132,3 +307,43 @@ func (ctx *Context) EditOrSend(smsg *SendMessage) (int, error) {
 
 	return msgid, err
 }
+
+// EditOrSendMedia replace media of the last message sent by bot, or send new media message
+func (ctx *Context) EditOrSendMedia(media InputMedia, opt ...SendOptions) (int, error) {
+	if msgid, ok := ctx.chat.editMessage(); ok || ctx.isInline() {
+		var markup []*InlineKeyboardMarkup
+		if len(opt) > 0 {
+			switch keys := opt[0].ReplyMarkup.(type) {
+			case *InlineKeyboardMarkup:
+				markup = append(markup, keys)
+			case InlineKeyboardMarkup:
+				markup = append(markup, &keys)
+			}
+		}
+
+		if len(opt) > 0 && media.ParseMode == "" {
+			media.ParseMode = opt[0].ParseMode
+		}
+
+		err := ctx.EditMedia(msgid, media, markup...)
+		return msgid, ignoreNotModified(err)
+	}
+
+	msg, err := ctx.Bot.SendMedia(ctx.Chat.ID, media, opt...)
+	if err != nil {
+		return msg.MessageID, err
+	}
+
+	ctx.chat.setEditMessageID(msg.MessageID)
+
+	return msg.MessageID, err
+}
+
+// ignoreNotModified return nil if message is not modified, because it already has the same content
+func ignoreNotModified(err error) error {
+	if errors.Is(err, ErrNotModified) {
+		return nil
+	}
+
+	return err
+}
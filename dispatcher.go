@@ -0,0 +1,220 @@
+package tebo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultWorkers is the default number of concurrently executed handlers
+var DefaultWorkers = 64
+
+// WithWorkers set the maximum number of concurrently executed handlers,
+// when all workers are busy receiving of updates is suspended
+func WithWorkers(n int) Option {
+	return func(b *Bot) {
+		if n > 0 {
+			b.workers = n
+		}
+	}
+}
+
+// job is update waiting for processing, parts of media group are passed together
+type job struct {
+	Update
+	group []Update
+}
+
+// updates return all updates of the job
+func (j job) updates() []Update {
+	if len(j.group) > 0 {
+		return j.group
+	}
+
+	return []Update{j.Update}
+}
+
+// chatQueue is updates of the chat waiting for its worker
+type chatQueue struct {
+	jobs []job
+}
+
+// dispatcher process updates of the same chat sequentially
+// and updates of different chats in parallel by bounded number of workers,
+// handler detached in ExpectAnswer runs concurrently with following updates of its chat
+type dispatcher struct {
+	bot   *Bot
+	slots chan struct{}
+
+	mu      sync.Mutex
+	queues  map[int]*chatQueue
+	dropped []Update
+
+	mediaGroups *mediaGroups
+
+	running atomic.Int32
+	wg      sync.WaitGroup
+}
+
+func newDispatcher(b *Bot, workers int) *dispatcher {
+	return &dispatcher{
+		bot:    b,
+		slots:  make(chan struct{}, workers),
+		queues: make(map[int]*chatQueue),
+	}
+}
+
+// enqueue add job to the queue if the chat already has a worker
+func (d *dispatcher) enqueue(chatid int, j job) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	q, ok := d.queues[chatid]
+	if ok {
+		q.jobs = append(q.jobs, j)
+	}
+
+	return ok
+}
+
+// dispatch pass update to the worker of its chat, it blocks while all workers are busy,
+// parts of media groups are collected before if aggregation is enabled,
+// pending albums of the chat are dispatched before the following update
+func (d *dispatcher) dispatch(u Update) {
+	if d.mediaGroups != nil {
+		if d.mediaGroups.add(u) {
+			return
+		}
+		d.mediaGroups.flushChat(updateKey(u))
+	}
+
+	d.dispatchJob(job{Update: u})
+}
+
+func (d *dispatcher) dispatchJob(j job) {
+	chatid := updateKey(j.Update)
+
+	if d.enqueue(chatid, j) {
+		return
+	}
+
+	select {
+	case d.slots <- struct{}{}:
+	default:
+		select {
+		case d.slots <- struct{}{}:
+		case <-d.bot.pollCtx.Done():
+			// bot is stopped while all workers are busy
+			d.mu.Lock()
+			d.dropped = append(d.dropped, j.updates()...)
+			d.mu.Unlock()
+			return
+		}
+	}
+
+	// while waiting for the slot another update could start the worker,
+	// check and registration of the queue must be done under the same lock
+	d.mu.Lock()
+	if q, ok := d.queues[chatid]; ok {
+		q.jobs = append(q.jobs, j)
+		d.mu.Unlock()
+		<-d.slots
+		return
+	}
+
+	q := new(chatQueue)
+	d.queues[chatid] = q
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	go d.work(chatid, q, j)
+}
+
+// work process updates of the chat until the queue is empty,
+// or the handler detach from the queue
+func (d *dispatcher) work(chatid int, q *chatQueue, j job) {
+	defer d.wg.Done()
+
+	for {
+		ctx := d.bot.newContext(j.Update)
+		for _, u := range j.group {
+			ctx.MediaGroup = append(ctx.MediaGroup, updateMessage(u))
+		}
+		ctx.detach = d.detacher(chatid, q)
+
+		d.running.Add(1)
+		d.bot.routeContext(ctx)
+		d.running.Add(-1)
+
+		d.mu.Lock()
+		if d.queues[chatid] != q {
+			// worker is detached, its slot is passed to the next worker
+			d.mu.Unlock()
+			return
+		}
+
+		if len(q.jobs) == 0 {
+			delete(d.queues, chatid)
+			d.mu.Unlock()
+			<-d.slots
+			return
+		}
+
+		j = q.jobs[0]
+		q.jobs = q.jobs[1:]
+		d.mu.Unlock()
+	}
+}
+
+// detacher return function which allows the handler to let following updates
+// of the chat be processed by another worker, handler waiting for an answer
+// in ExpectAnswer calls it, otherwise the answer is queued behind the handler
+func (d *dispatcher) detacher(chatid int, q *chatQueue) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+
+			if d.queues[chatid] != q {
+				return
+			}
+
+			if len(q.jobs) == 0 {
+				delete(d.queues, chatid)
+				<-d.slots
+				return
+			}
+
+			next := &chatQueue{jobs: q.jobs[1:]}
+			d.queues[chatid] = next
+
+			d.wg.Add(1)
+			go d.work(chatid, next, q.jobs[0])
+		})
+	}
+}
+
+// wait until all workers are done
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
+
+// abandon clear queues, so workers exit after the current update,
+// return number of running handlers and updates not passed to handlers
+func (d *dispatcher) abandon() (running int, pending []Update) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending = append(pending, d.dropped...)
+	d.dropped = nil
+
+	for _, q := range d.queues {
+		for _, j := range q.jobs {
+			pending = append(pending, j.updates()...)
+		}
+		q.jobs = nil
+	}
+
+	return int(d.running.Load()), pending
+}
@@ -0,0 +1,200 @@
+package tebo
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func textUpdate(id, chatid int, text string) Update {
+	return Update{
+		UpdateID: id,
+		Message: Message{
+			MessageID: id,
+			Chat:      Chat{ID: chatid, Type: "private"},
+			Text:      text,
+		},
+	}
+}
+
+func TestDispatcherOrder(t *testing.T) {
+	b := newOfflineBot(t)
+
+	var mu sync.Mutex
+	got := make(map[int][]string)
+
+	b.Handle(".*", func(ctx *Context) *SendMessage {
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		got[ctx.Chat.ID] = append(got[ctx.Chat.ID], ctx.Text)
+		mu.Unlock()
+		return nil
+	})
+
+	for i := 0; i < 20; i++ {
+		for chatid := 1; chatid <= 3; chatid++ {
+			b.dispatcher.dispatch(textUpdate(i, chatid, fmt.Sprint(i)))
+		}
+	}
+
+	b.dispatcher.wait()
+
+	for chatid := 1; chatid <= 3; chatid++ {
+		if len(got[chatid]) != 20 {
+			t.Fatalf("chat %d: expected 20 updates, got %d", chatid, len(got[chatid]))
+		}
+		for i, text := range got[chatid] {
+			if text != fmt.Sprint(i) {
+				t.Errorf("chat %d: updates are processed out of order: %v", chatid, got[chatid])
+				break
+			}
+		}
+	}
+}
+
+func TestDispatcherWorkers(t *testing.T) {
+	b := newOfflineBot(t)
+	b.dispatcher = newDispatcher(b, 2)
+
+	var mu sync.Mutex
+	var active, max int
+
+	b.Handle(".*", func(ctx *Context) *SendMessage {
+		mu.Lock()
+		if active++; active > max {
+			max = active
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	})
+
+	for chatid := 1; chatid <= 6; chatid++ {
+		b.dispatcher.dispatch(textUpdate(chatid, chatid, "text"))
+	}
+
+	b.dispatcher.wait()
+
+	if max != 2 {
+		t.Errorf("expected 2 concurrent handlers, got %d", max)
+	}
+}
+
+func TestDispatcherExpectAnswer(t *testing.T) {
+	b := newOfflineBot(t)
+
+	answer := make(chan string, 1)
+	b.Handle("/ask", func(ctx *Context) *SendMessage {
+		actx, ok := ctx.ExpectAnswer()
+		if ok {
+			answer <- actx.Text
+		}
+		return nil
+	})
+
+	b.dispatcher.dispatch(textUpdate(1, 1, "/ask"))
+	b.dispatcher.dispatch(textUpdate(2, 1, "yes"))
+
+	select {
+	case text := <-answer:
+		if text != "yes" {
+			t.Errorf("unexpected answer %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("answer is not received")
+	}
+
+	b.dispatcher.wait()
+}
+
+func TestDispatcherConcurrent(t *testing.T) {
+	b := newOfflineBot(t)
+	b.dispatcher = newDispatcher(b, 4)
+
+	var mu sync.Mutex
+	var active, max, count int
+
+	b.Handle(".*", func(ctx *Context) *SendMessage {
+		mu.Lock()
+		if active++; active > max {
+			max = active
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		active--
+		count++
+		mu.Unlock()
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				b.dispatcher.dispatch(textUpdate(i*10+n, 1, "text"))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	b.dispatcher.wait()
+
+	if count != 80 {
+		t.Errorf("expected 80 updates, got %d", count)
+	}
+	if max != 1 {
+		t.Errorf("updates of the same chat are processed in parallel by %d handlers", max)
+	}
+	if n := len(b.dispatcher.slots); n != 0 {
+		t.Errorf("%d slots are leaked", n)
+	}
+}
+
+func TestDispatcherDetachedHandler(t *testing.T) {
+	api := newFakeAPI(t)
+
+	var mu sync.Mutex
+	var msgid int
+	api.Handle("sendMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		mu.Lock()
+		msgid++
+		id := msgid
+		mu.Unlock()
+		return Message{MessageID: id, Chat: Chat{ID: 1}}, nil
+	})
+	api.Handle("editMessageText", func(r *http.Request) (interface{}, *ErrorResponse) {
+		return Message{MessageID: 1, Chat: Chat{ID: 1}}, nil
+	})
+
+	b := newTestBot(t, api, WithRateLimit(RateLimit{}))
+
+	// command cancels the waiting, so the rest of /ask runs concurrently with /other
+	b.Handle("/ask", func(ctx *Context) *SendMessage {
+		ctx.ExpectAnswer()
+		ctx.EditOrSendMessage("asked")
+		return nil
+	})
+	b.Handle("/other", func(ctx *Context) *SendMessage {
+		ctx.EditOrSendMessage("other")
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		b.dispatcher.dispatch(textUpdate(2*i, 1, "/ask"))
+		b.dispatcher.dispatch(textUpdate(2*i+1, 1, "/other"))
+	}
+	b.dispatcher.wait()
+}
@@ -0,0 +1,141 @@
+package tebo
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MessageRef identify message to edit: message of the chat
+// or message sent by user via inline mode of the bot
+type MessageRef struct {
+	ChatID          int    `json:"chat_id,omitempty"`
+	MessageID       int    `json:"message_id,omitempty"`
+	InlineMessageID string `json:"inline_message_id,omitempty"`
+}
+
+func ChatMessage(chatid, messageid int) MessageRef {
+	return MessageRef{ChatID: chatid, MessageID: messageid}
+}
+
+func InlineMessage(inlineMessageID string) MessageRef {
+	return MessageRef{InlineMessageID: inlineMessageID}
+}
+
+// edit send edit request, telegram return edited message for messages
+// of chats and true for inline messages, then empty message is returned
+func (b *Bot) edit(method string, payload interface{}, files ...FormFile) (msg Message, err error) {
+	var result json.RawMessage
+
+	if len(files) == 0 {
+		err = b.Request(method, payload, &result)
+	} else {
+		err = b.FilesRequest(method, files, payload, &result)
+	}
+	if err != nil {
+		return
+	}
+
+	if len(result) > 0 && result[0] == '{' {
+		err = json.Unmarshal(result, &msg)
+	}
+
+	return
+}
+
+type ReqEditMessage struct {
+	MessageRef  `json:",squash"`
+	SendMessage `json:",squash"`
+}
+
+func (b *Bot) EditMessage(chatid int, messageid int, smsg *SendMessage) (msg Message, err error) {
+	return b.EditMessageText(ChatMessage(chatid, messageid), smsg)
+}
+
+func (b *Bot) EditMessageText(ref MessageRef, smsg *SendMessage) (msg Message, err error) {
+	if len(smsg.Text) == 0 {
+		return msg, errors.New("text is empty")
+	}
+
+	return b.edit("editMessageText", ReqEditMessage{MessageRef: ref, SendMessage: *smsg})
+}
+
+type ReqEditMessageCaption struct {
+	MessageRef  `json:",squash"`
+	Caption     string      `json:"caption"`
+	ParseMode   string      `json:"parse_mode,omitempty"`
+	ReplyMarkup interface{} `json:"reply_markup,omitempty"`
+}
+
+// EditMessageCaption change caption of the media message, parse mode and
+// reply markup are taken from options
+func (b *Bot) EditMessageCaption(ref MessageRef, caption string, opt ...SendOptions) (Message, error) {
+	req := ReqEditMessageCaption{MessageRef: ref, Caption: caption}
+	if len(opt) > 0 {
+		req.ParseMode = opt[0].ParseMode
+		req.ReplyMarkup = opt[0].ReplyMarkup
+	}
+
+	return b.edit("editMessageCaption", req)
+}
+
+type ReqEditMessageReplyMarkup struct {
+	MessageRef  `json:",squash"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// EditMessageReplyMarkup change inline keyboard of the message, nil removes it
+func (b *Bot) EditMessageReplyMarkup(ref MessageRef, markup *InlineKeyboardMarkup) (Message, error) {
+	return b.edit("editMessageReplyMarkup", ReqEditMessageReplyMarkup{MessageRef: ref, ReplyMarkup: markup})
+}
+
+type ReqEditMessageMedia struct {
+	MessageRef  `json:",squash"`
+	Media       InputMedia            `json:"media"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// EditMessageMedia replace media of the message by new uploaded file or by file_id/URL,
+// file of the inline message can not be uploaded
+func (b *Bot) EditMessageMedia(ref MessageRef, media InputMedia, markup ...*InlineKeyboardMarkup) (Message, error) {
+	req := ReqEditMessageMedia{MessageRef: ref}
+	if len(markup) > 0 {
+		req.ReplyMarkup = markup[0]
+	}
+
+	files, err := media.attach("media")
+	if err != nil {
+		return Message{}, err
+	}
+	req.Media = media
+
+	return b.edit("editMessageMedia", req, files...)
+}
+
+type ReqEditMessageLiveLocation struct {
+	MessageRef  `json:",squash"`
+	Location    `json:",squash"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// EditMessageLiveLocation move live location sent with live period until it expires or stopped
+func (b *Bot) EditMessageLiveLocation(ref MessageRef, loc Location, markup ...*InlineKeyboardMarkup) (Message, error) {
+	req := ReqEditMessageLiveLocation{MessageRef: ref, Location: loc}
+	if len(markup) > 0 {
+		req.ReplyMarkup = markup[0]
+	}
+
+	// live period can not be changed
+	req.LivePeriod = 0
+
+	return b.edit("editMessageLiveLocation", req)
+}
+
+// StopMessageLiveLocation stop updating of the live location
+func (b *Bot) StopMessageLiveLocation(ref MessageRef, markup ...*InlineKeyboardMarkup) (Message, error) {
+	req := ReqEditMessageReplyMarkup{MessageRef: ref}
+	if len(markup) > 0 {
+		req.ReplyMarkup = markup[0]
+	}
+
+	return b.edit("stopMessageLiveLocation", req)
+}
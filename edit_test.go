@@ -0,0 +1,63 @@
+package tebo
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestEditMessage(t *testing.T) {
+	api := newFakeAPI(t)
+
+	requests := make(map[string]map[string]json.RawMessage)
+	handle := func(method string, result interface{}) {
+		api.Handle(method, func(r *http.Request) (interface{}, *ErrorResponse) {
+			var req map[string]json.RawMessage
+			decodeRequest(r, &req)
+			requests[method] = req
+			return result, nil
+		})
+	}
+
+	handle("editMessageCaption", Message{MessageID: 5, Caption: "new"})
+	handle("editMessageMedia", Message{MessageID: 5})
+	handle("editMessageText", true)
+
+	b := newTestBot(t, api)
+
+	msg, err := b.EditMessageCaption(ChatMessage(10, 5), "new", SendOptions{ParseMode: ParseModeHTML})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Caption != "new" {
+		t.Errorf("unexpected edited message %+v", msg)
+	}
+	if req := requests["editMessageCaption"]; string(req["chat_id"]) != "10" || string(req["parse_mode"]) != `"HTML"` {
+		t.Errorf("unexpected editMessageCaption request %v", req)
+	}
+
+	_, err = b.EditMessageMedia(ChatMessage(10, 5), NewInputMediaDocument(FileByURL("https://example.com/a.pdf"), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var media InputMedia
+	json.Unmarshal(requests["editMessageMedia"]["media"], &media)
+	if media.Type != InputMediaDocument || media.Media != "https://example.com/a.pdf" {
+		t.Errorf("unexpected media %+v", media)
+	}
+
+	// inline messages are edited by callback queries from them
+	ctx := b.newContext(Update{CallbackQuery: &CallbackQuery{ID: "1", From: User{ID: 10}, InlineMessageID: "abc"}})
+	if _, err := ctx.EditOrSendMessage("text"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := requests["editMessageText"]
+	if string(req["inline_message_id"]) != `"abc"` {
+		t.Errorf("inline message is not edited: %v", req)
+	}
+	if _, ok := req["chat_id"]; ok {
+		t.Errorf("inline message is edited with chat id: %v", req)
+	}
+}
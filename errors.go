@@ -0,0 +1,107 @@
+package tebo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Classes of telegram errors, ErrorResponse matches them by errors.Is:
+//
+//	if errors.Is(err, tebo.ErrBlocked) {
+//		// user blocked the bot
+//	}
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrBlocked         = errors.New("bot is blocked or kicked from the chat")
+	ErrChatNotFound    = errors.New("chat not found")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotModified     = errors.New("message is not modified")
+	ErrChatMigrated    = errors.New("group chat was upgraded to a supergroup")
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// Is report whether the error belongs to the class, all classes except
+// unauthorized, blocked and too many requests are also bad requests
+func (e *ErrorResponse) Is(target error) bool {
+	desc := strings.ToLower(e.Description)
+
+	switch target {
+	case ErrBadRequest:
+		return e.ErrorCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.ErrorCode == http.StatusUnauthorized
+	case ErrBlocked:
+		return e.ErrorCode == http.StatusForbidden
+	case ErrTooManyRequests:
+		return e.ErrorCode == http.StatusTooManyRequests
+	case ErrChatMigrated:
+		return e.MigrateToChatID() != 0
+	case ErrChatNotFound:
+		return e.ErrorCode == http.StatusBadRequest && strings.Contains(desc, "chat not found")
+	case ErrNotModified:
+		return e.ErrorCode == http.StatusBadRequest && strings.Contains(desc, "message is not modified")
+	case ErrMessageNotFound:
+		// message to edit/delete/forward/copy/reply not found
+		return e.ErrorCode == http.StatusBadRequest &&
+			strings.Contains(desc, "message") && strings.Contains(desc, "not found")
+	}
+
+	return false
+}
+
+// MigrateToChatID return new id of the group upgraded to a supergroup
+func (e *ErrorResponse) MigrateToChatID() int {
+	if e.Parameters == nil {
+		return 0
+	}
+
+	return e.Parameters.MigrateToChatID
+}
+
+// RetryAfter return time to wait before the request can be repeated,
+// if err is too many requests error
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *ErrorResponse
+	if errors.As(err, &e) && e.ErrorCode == http.StatusTooManyRequests {
+		return e.RetryAfter(), true
+	}
+
+	return 0, false
+}
+
+// MigratedTo return new id of the chat, if err is chat migrated error
+func MigratedTo(err error) (int, bool) {
+	var e *ErrorResponse
+	if errors.As(err, &e) && e.MigrateToChatID() != 0 {
+		return e.MigrateToChatID(), true
+	}
+
+	return 0, false
+}
+
+// errorClasses are checked by ErrorClass in order from specific to common
+var errorClasses = []error{
+	ErrChatMigrated,
+	ErrChatNotFound,
+	ErrMessageNotFound,
+	ErrNotModified,
+	ErrBlocked,
+	ErrTooManyRequests,
+	ErrUnauthorized,
+	ErrBadRequest,
+}
+
+// ErrorClass return the most specific class of telegram error,
+// nil for network and other errors
+func ErrorClass(err error) error {
+	for _, class := range errorClasses {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+
+	return nil
+}
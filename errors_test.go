@@ -0,0 +1,66 @@
+package tebo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestErrorClasses(t *testing.T) {
+	testCases := []struct {
+		err     *ErrorResponse
+		classes []error
+	}{
+		{&ErrorResponse{ErrorCode: 403, Description: "Forbidden: bot was blocked by the user"}, []error{ErrBlocked}},
+		{&ErrorResponse{ErrorCode: 401, Description: "Unauthorized"}, []error{ErrUnauthorized}},
+		{&ErrorResponse{ErrorCode: 400, Description: "Bad Request: chat not found"}, []error{ErrBadRequest, ErrChatNotFound}},
+		{&ErrorResponse{ErrorCode: 400, Description: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same"}, []error{ErrBadRequest, ErrNotModified}},
+		{&ErrorResponse{ErrorCode: 400, Description: "Bad Request: message to edit not found"}, []error{ErrBadRequest, ErrMessageNotFound}},
+		{&ErrorResponse{ErrorCode: 400, Description: "Bad Request: group chat was upgraded to a supergroup chat", Parameters: &ResponseParameters{MigrateToChatID: -100123}}, []error{ErrBadRequest, ErrChatMigrated}},
+		{&ErrorResponse{ErrorCode: 429, Description: "Too Many Requests: retry after 5", Parameters: &ResponseParameters{RetryAfter: 5}}, []error{ErrTooManyRequests}},
+	}
+
+	all := []error{ErrBadRequest, ErrUnauthorized, ErrBlocked, ErrChatNotFound, ErrMessageNotFound, ErrNotModified, ErrChatMigrated, ErrTooManyRequests}
+
+	for _, tc := range testCases {
+		// errors are matched through wrapping
+		err := fmt.Errorf("request failed: %w", tc.err)
+
+		for _, class := range all {
+			expected := false
+			for _, c := range tc.classes {
+				expected = expected || c == class
+			}
+
+			if errors.Is(err, class) != expected {
+				t.Errorf("%q: expected errors.Is(%q) to be %v", tc.err.Description, class, expected)
+			}
+		}
+	}
+
+	if d, ok := RetryAfter(testCases[6].err); !ok || d != 5*time.Second {
+		t.Errorf("unexpected retry after %v", d)
+	}
+
+	if id, ok := MigratedTo(testCases[5].err); !ok || id != -100123 {
+		t.Errorf("unexpected migrated chat id %d", id)
+	}
+}
+
+func TestEditOrSendNotModified(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Handle("editMessageText", func(*http.Request) (interface{}, *ErrorResponse) {
+		return nil, &ErrorResponse{ErrorCode: 400, Description: "Bad Request: message is not modified"}
+	})
+
+	b := newTestBot(t, api)
+
+	ctx := b.newContext(textUpdate(1, 10, "hello"))
+	ctx.chat.setEditMessageID(5)
+
+	if _, err := ctx.EditOrSendMessage("same text"); err != nil {
+		t.Errorf("not modified message should not be an error: %v", err)
+	}
+}
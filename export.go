@@ -0,0 +1,206 @@
+package tebo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+)
+
+// exportedUpdate is update as it is sent by telegram, message is omitted for other kinds
+type exportedUpdate struct {
+	Update
+	Message *Message `json:"message,omitempty"`
+}
+
+// ExportHistory write stored updates selected by the filter as JSON Lines,
+// each line is an update in the format of Bot API
+func (b *Bot) ExportHistory(w io.Writer, filter HistoryQuery) error {
+	bw := bufio.NewWriter(w)
+
+	var err error
+	encode := func(u Update) bool {
+		e := exportedUpdate{Update: u}
+		if u.Message.MessageID != 0 || u.Message.Chat.ID != 0 {
+			e.Message = &u.Message
+		}
+
+		var data []byte
+		if data, err = exportJSON(e); err != nil {
+			return false
+		}
+
+		_, err = bw.Write(append(data, '\n'))
+		return err == nil
+	}
+
+	if filter.LastPerChat > 0 {
+		updates, e := b.QueryUpdates(filter)
+		if e != nil {
+			return e
+		}
+
+		for _, u := range updates {
+			if !encode(u) {
+				break
+			}
+		}
+	} else {
+		chats, store, e := b.queryHistory(filter)
+		if e != nil {
+			return e
+		}
+
+		e = store.Range(func(u Update) bool {
+			if !filter.match(u, chats) {
+				return true
+			}
+			return encode(u)
+		})
+		if e != nil {
+			return e
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// exportJSON encode the update, users with zero id are removed, they are left
+// by messages without sender, such as channel posts and service records
+func exportJSON(e exportedUpdate) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	pruneZeroUsers(v)
+
+	return json.Marshal(v)
+}
+
+func pruneZeroUsers(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if user, ok := val.(map[string]interface{}); ok && key == "from" && user["id"] == json.Number("0") {
+				delete(v, key)
+				continue
+			}
+			pruneZeroUsers(val)
+		}
+	case []interface{}:
+		for _, val := range v {
+			pruneZeroUsers(val)
+		}
+	}
+}
+
+// importBatch is number of updates appended to the store at once
+const importBatch = 1000
+
+// ImportHistory append updates from JSON Lines to the history and restore chats,
+// updates already stored are skipped, so the same dump can be imported again,
+// return number of imported updates. Imported updates do not change
+// Bot.UpdateID, so the offset of polling is kept
+func (b *Bot) ImportHistory(r io.Reader) (n int, err error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	stored, err := b.storedUpdates()
+	if err != nil {
+		return 0, err
+	}
+
+	var batch []Update
+	for {
+		var u Update
+		if err = dec.Decode(&u); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+
+		key, err := updateDigest(u)
+		if err != nil {
+			return n, err
+		}
+		if stored[key] {
+			continue
+		}
+		stored[key] = true
+
+		u.Imported = true
+		if batch = append(batch, u); len(batch) == importBatch {
+			if err = b.importUpdates(batch); err != nil {
+				return n, err
+			}
+			n += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err = b.importUpdates(batch); err != nil {
+			return n, err
+		}
+		n += len(batch)
+	}
+
+	return n, nil
+}
+
+func (b *Bot) importUpdates(updates []Update) error {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+
+	return b.appendHistory(updates)
+}
+
+// updateDigest return hash of the update encoded to JSON,
+// updates are compared by content, since imported ids may collide with received ones
+func updateDigest(u Update) ([sha256.Size]byte, error) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(data), nil
+}
+
+// storedUpdates return digests of all stored updates
+func (b *Bot) storedUpdates() (map[[sha256.Size]byte]bool, error) {
+	_, store, err := b.queryHistory(HistoryQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	var digestErr error
+	stored := make(map[[sha256.Size]byte]bool)
+	err = store.Range(func(u Update) bool {
+		key, err := updateDigest(u)
+		if err != nil {
+			digestErr = err
+			return false
+		}
+
+		stored[key] = true
+		return true
+	})
+	if err == nil {
+		err = digestErr
+	}
+
+	return stored, err
+}
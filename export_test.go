@@ -0,0 +1,98 @@
+package tebo
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportImportHistory(t *testing.T) {
+	b := newBot(testToken, WithHistoryStore(NewMemoryHistory()))
+	if err := b.readHistory(""); err != nil {
+		t.Fatal(err)
+	}
+	defer b.closeHistory()
+
+	b.updateHistory([]Update{
+		textUpdate(1, 10, "hello"),
+		{UpdateID: 2, CallbackQuery: &CallbackQuery{ID: "1", From: User{ID: 10}, Message: Message{MessageID: 1, Chat: Chat{ID: 10}}}},
+		{UpdateID: 3, Message: Message{MessageID: 3, Chat: Chat{ID: -1, Type: "group", Title: "team"}, MigrateToChatID: -100}},
+		textUpdate(4, 20, "other"),
+		{Message: Message{Chat: Chat{ID: -2, Type: "group"}, Date: 1, MigrateToChatID: -200}},
+	})
+
+	var buf bytes.Buffer
+	if err := b.ExportHistory(&buf, HistoryQuery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("unexpected export:\n%s", buf.String())
+	}
+
+	// updates have the format of Bot API
+	var callback map[string]json.RawMessage
+	json.Unmarshal([]byte(lines[1]), &callback)
+	if _, ok := callback["message"]; ok || callback["callback_query"] == nil || string(callback["update_id"]) != "2" {
+		t.Errorf("unexpected exported callback query %s", lines[1])
+	}
+
+	var filtered bytes.Buffer
+	b.ExportHistory(&filtered, HistoryQuery{ChatID: 20})
+	if n := strings.Count(filtered.String(), "\n"); n != 1 {
+		t.Errorf("unexpected number of exported updates of the chat %d", n)
+	}
+
+	// users of messages without sender are not exported
+	if strings.Contains(buf.String(), `"from":{"id":0`) {
+		t.Errorf("user with zero id is exported:\n%s", buf.String())
+	}
+
+	// import to the new bot which polled updates with greater ids
+	store := NewMemoryHistory()
+	store.Append([]Update{textUpdate(500, 30, "polled")})
+
+	nb := newBot(testToken, WithHistoryStore(store))
+	if err := nb.readHistory(""); err != nil {
+		t.Fatal(err)
+	}
+	defer nb.closeHistory()
+
+	n, err := nb.ImportHistory(bytes.NewReader(buf.Bytes()))
+	if err != nil || n != 5 {
+		t.Fatalf("imported %d updates: %v", n, err)
+	}
+
+	// offset of polling is not changed by import
+	if nb.UpdateID != 500 {
+		t.Errorf("unexpected last update id %d", nb.UpdateID)
+	}
+	if id, _ := store.LastUpdateID(); id != 500 {
+		t.Errorf("unexpected stored last update id %d", id)
+	}
+	for from, to := range map[int]int{-1: -100, -2: -200} {
+		if id, ok := nb.Chats.Migrated(from); !ok || id != to {
+			t.Errorf("migration of %d is not imported", from)
+		}
+	}
+	if _, ok := nb.Chats.chats.Load(20); !ok {
+		t.Errorf("chat is not imported")
+	}
+
+	// the same dump is not imported twice
+	if n, err := nb.ImportHistory(bytes.NewReader(buf.Bytes())); err != nil || n != 0 {
+		t.Errorf("imported %d updates again: %v", n, err)
+	}
+
+	// imported updates follow the polled one
+	var expected, again bytes.Buffer
+	nb.ExportHistory(&expected, HistoryQuery{ChatID: 30})
+	expected.Write(buf.Bytes())
+
+	nb.ExportHistory(&again, HistoryQuery{})
+	if again.String() != expected.String() {
+		t.Errorf("export of imported history differs:\n%s\n%s", expected.String(), again.String())
+	}
+}
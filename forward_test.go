@@ -0,0 +1,57 @@
+package tebo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestForwardAndCopy(t *testing.T) {
+	api := newFakeAPI(t)
+
+	requests := make(map[string]ReqCopyMessage)
+	for _, method := range []string{"forwardMessages", "copyMessages"} {
+		method := method
+		api.Handle(method, func(r *http.Request) (interface{}, *ErrorResponse) {
+			var req ReqCopyMessage
+			decodeRequest(r, &req)
+			requests[method] = req
+
+			var ids []MessageID
+			for _, id := range req.MessageIDs {
+				ids = append(ids, MessageID{MessageID: id + 100})
+			}
+			return ids, nil
+		})
+	}
+	api.Handle("copyMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		return MessageID{MessageID: 7}, nil
+	})
+
+	b := newTestBot(t, api)
+
+	ctx := b.newContext(textUpdate(1, 10, "hello"))
+	ctx.Message.MessageID = 3
+
+	ids, err := ctx.Forward(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := requests["forwardMessages"]; req.ChatID != 20 || req.FromChatID != 10 || len(ids) != 1 || ids[0] != 103 {
+		t.Errorf("unexpected forward %+v, ids %v", req, ids)
+	}
+
+	// album is copied at once
+	ctx.MediaGroup = []Message{{MessageID: 3}, {MessageID: 4}}
+	ids, err = ctx.CopyTo(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[1] != 104 {
+		t.Errorf("unexpected copied messages %v", ids)
+	}
+
+	id, err := b.CopyMessage(20, 10, 3)
+	if err != nil || id != 7 {
+		t.Errorf("unexpected copied message %d, %v", id, err)
+	}
+}
@@ -107,7 +107,7 @@ func (fsm *FSM) isRootID(id string) bool {
 }
 
 func (fsm *FSM) handle(ctx *Context) (err error) {
-	ctx.chat.fsm = fsm.root
+	ctx.chat.setFSM(fsm.root)
 
 	if ctx.CallbackQuery == nil {
 		return fsm.initialMessage(ctx)
@@ -240,7 +240,7 @@ func (fsm *FSM) Button(text string) InlineKeyboardButton {
 }
 
 func (fsm *FSM) NewMessage(ctx *Context, text string, opt ...SendOptions) *SendMessage {
-	ctx.chat.fsm = fsm
+	ctx.chat.setFSM(fsm)
 
 	smsg := ctx.NewMessage(text, opt...)
 	smsg.ReplyMarkup = fsm.keyboard(ctx)
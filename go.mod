@@ -4,7 +4,6 @@ go 1.21.1
 
 require (
 	github.com/imroc/req/v3 v3.42.2
-	github.com/mitchellh/mapstructure v1.5.0
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
 	github.com/vmihailenco/msgpack/v5 v5.4.1
 )
@@ -39,10 +39,13 @@ func (b *Bot) Handle(cmd string, f HandleFunc, mid ...MiddlewareFunc) error {
 }
 
 func (b *Bot) Start() {
+	b.polling.Add(1)
+	defer b.polling.Done()
+
 	t := time.Now()
 
-	for !b.closed {
-		updates, err := b.loadUpdates()
+	for !b.closed.Load() {
+		updates, err := b.loadUpdates(b.pollCtx)
 		if err != nil {
 			if !errors.Is(err, context.Canceled) {
 				log.Warning(err)
@@ -50,7 +53,7 @@ func (b *Bot) Start() {
 		} else {
 			// try to lookup apporpriate handler for this update
 			for _, u := range updates {
-				go b.route(u)
+				b.dispatcher.dispatch(u)
 			}
 		}
 
@@ -58,17 +61,31 @@ func (b *Bot) Start() {
 			t = time.Now()
 		}
 
+		interval := ShortPollInterval
 		if time.Since(t) > time.Minute {
-			time.Sleep(PollInterval)
-		} else {
-			time.Sleep(ShortPollInterval)
+			interval = PollInterval
 		}
 
+		if sleep(b.pollCtx, interval) != nil {
+			return
+		}
 	}
 }
 
 func (b *Bot) route(u Update) {
-	ctx := b.newContext(u)
+	b.routeContext(b.newContext(u))
+}
+
+func (b *Bot) routeContext(ctx *Context) {
+	u := ctx.Update
+
+	// context passed to the waiting handler is finished by that handler
+	handover := false
+	defer func() {
+		if !handover {
+			ctx.finish()
+		}
+	}()
 
 	// pass context for each updates handler, if it return false then stop further process
 	for _, h := range b.updatesHandlers {
@@ -77,27 +94,26 @@ func (b *Bot) route(u Update) {
 		}
 	}
 
-	// if expect enable
-	if ctx.chat.expectContext != nil && ctx.chat.expectCancel != nil {
-		// if message is command, started from '/', then cancel expect channels
-		// and lookup appropriate handler for this command
-		if len(u.Message.Text) > 0 && u.Message.Text[0] == '/' {
-			ctx.chat.expectCancel <- true
-			ctx.chat.closeExpectChannels()
-		} else {
+	// updates other than messages and callback queries have own handlers
+	if b.routeKind(ctx) {
+		return
+	}
 
-			// if message is not command then send this message to expect channel
-			// and then do not lookup handler for this message
-			ctx.chat.expectContext <- ctx
-			ctx.chat.closeExpectChannels()
-			return
-		}
+	// if message is command, started from '/', then cancel expectation
+	// and lookup appropriate handler for this command
+	if len(u.Message.Text) > 0 && u.Message.Text[0] == '/' {
+		ctx.chat.answer(nil)
+	} else if ctx.chat.answer(ctx) {
+		// if message is not command then pass it to the waiting handler
+		// and then do not lookup handler for this message
+		handover = true
+		return
 	}
 
 	// if for chat enable FSM and its not a bot command, then pass context to it
-	if ctx.chat.fsm != nil {
+	if fsm := ctx.chat.getFSM(); fsm != nil {
 		if _, ok := u.Message.BotCommand(); !ok {
-			if err := ctx.chat.fsm.handle(ctx); err != nil {
+			if err := fsm.handle(ctx); err != nil {
 				log.Error("fsm error:", err)
 			}
 			return
@@ -115,13 +131,6 @@ func (b *Bot) route(u Update) {
 // ExecuteHandler parse the incoming message text, lookup a suitable handler,
 // execute middlewares and send the reponse
 func (b *Bot) ExecuteHandler(ctx *Context) (err error) {
-	defer func() {
-		if e := recover(); e != nil {
-			err = fmt.Errorf("%s\n%s", e, debug.Stack())
-			log.Error(err)
-		}
-	}()
-
 	// lookup a handler by the received command
 	h, ok := b.lookupHandler(ctx.Message.Text)
 	if !ok {
@@ -130,6 +139,19 @@ func (b *Bot) ExecuteHandler(ctx *Context) (err error) {
 		// return fmt.Errorf("command %s, handler not found", ctx.Text)
 	}
 
+	return b.execute(ctx, h)
+}
+
+// execute middlewares and handler, and send the response
+func (b *Bot) execute(ctx *Context, h handler) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%s\n%s", e, debug.Stack())
+			log.Error(err)
+		}
+	}()
+
+	var ok bool
 	f := h.callback
 
 	// execute global middlewares
@@ -152,6 +174,10 @@ func (b *Bot) ExecuteHandler(ctx *Context) (err error) {
 		return nil
 	}
 
+	if ctx.Chat.ID == 0 {
+		return fmt.Errorf("%s has no chat to send the response", ctx.Kind())
+	}
+
 	_, err = ctx.Send(smsg)
 	return err
 }
@@ -1,77 +1,184 @@
 package tebo
 
 import (
-	"bufio"
-	"fmt"
-	"os"
-
-	"github.com/vmihailenco/msgpack/v5"
+	"context"
+	"errors"
+	"strconv"
+	"time"
 )
 
 func (b *Bot) addChat(u Update) {
-	if u.CallbackQuery != nil {
-		b.Chats.Get(u.CallbackQuery.Message.Chat)
-	} else {
-		b.Chats.Get(u.Message.Chat)
+	info, changed := b.Chats.update(u)
+	if info.ID == 0 {
+		return
+	}
+
+	if changed {
+		b.saveChat(info)
+	}
+
+	// group upgraded to supergroup sends service messages to both chats
+	if msg := updateMessage(u); msg.MigrateToChatID != 0 {
+		b.migrateChats(info.ID, msg.MigrateToChatID)
+	} else if msg.MigrateFromChatID != 0 {
+		b.migrateChats(msg.MigrateFromChatID, info.ID)
+	}
+}
+
+// migrateChats move the group to the new id and save info of the supergroup
+func (b *Bot) migrateChats(from, to int) bool {
+	if !b.Chats.migrate(from, to) {
+		return false
 	}
+
+	if info, ok := b.Chats.Info(to); ok {
+		b.saveChat(info)
+	}
+
+	return true
 }
 
+var errHistoryClosed = errors.New("history is closed")
+
+// migrateChat follow migration of the group known from the error response,
+// it is stored in history as service message
+func (b *Bot) migrateChat(from, to int) {
+	info, _ := b.Chats.Info(from)
+	if !b.migrateChats(from, to) {
+		return
+	}
+
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+
+	if b.history == nil {
+		return
+	}
+
+	u := Update{Message: Message{
+		Chat:            Chat{ID: from, Type: "group", Title: info.Title, Username: info.Username},
+		Date:            time.Now().Unix(),
+		MigrateToChatID: to,
+	}}
+
+	if err := b.history.Append([]Update{u}); err != nil {
+		log.Errorf("failed to store migration of the chat %d: %v", from, err)
+	}
+}
+
+// followMigration update registry if the request failed because the group
+// is upgraded to supergroup, return new id of the chat
+func (b *Bot) followMigration(chatid string, err error) (int, bool) {
+	to, ok := MigratedTo(err)
+	if !ok {
+		return 0, false
+	}
+
+	if from, err := strconv.Atoi(chatid); err == nil {
+		b.migrateChat(from, to)
+	}
+
+	return to, true
+}
+
+// readHistory open the history file and the file of chats, if stores are not set
+// by options, and restore known chats and the last update id
 func (b *Bot) readHistory(filename string) (err error) {
-	b.historyFile, err = os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0666)
-	if err != nil {
-		return err
+	if b.history == nil {
+		if b.history, err = NewFileHistory(filename, b.historyPolicy); err != nil {
+			return err
+		}
+	}
+
+	if b.chatStore == nil && filename != "" {
+		if b.chatStore, err = NewFileChats(filename+".chats", b.historyPolicy.Keys); err != nil {
+			return err
+		}
 	}
 
-	s := bufio.NewScanner(b.historyFile)
-	for s.Scan() {
-		var u Update
-		if err := msgpack.Unmarshal(s.Bytes(), &u); err != nil {
-			// log.Error(err)
-			continue
+	if b.chatStore != nil {
+		err = b.chatStore.Range(func(info ChatInfo) bool {
+			b.Chats.store(info)
+			return true
+		})
+		if err != nil {
+			return err
 		}
+	}
 
-		// if u.UpdateID > b.UpdateID {
-		b.UpdateID = u.UpdateID
-		// }
+	// chats of the history are known before the store of chats was added
+	err = b.history.Chats(func(chat Chat) bool {
+		b.Chats.Get(chat)
+		return true
+	})
+	if err != nil {
+		return err
+	}
 
-		b.addChat(u)
+	err = b.history.Migrations(func(from, to int) bool {
+		b.Chats.migrate(from, to)
+		return true
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	b.UpdateID, err = b.history.LastUpdateID()
+	return err
 }
 
 func (b *Bot) updateHistory(updates []Update) error {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+
+	if err := b.appendHistory(updates); err != nil {
+		return err
+	}
+
 	var maxUpdateID int
 	for _, u := range updates {
 		if u.UpdateID > maxUpdateID {
 			maxUpdateID = u.UpdateID
 		}
+	}
 
-		b.addChat(u)
+	if maxUpdateID > 0 {
+		b.UpdateID = maxUpdateID
+	}
 
-		// log.Debugf("%+v", u)
+	return nil
+}
 
-		line, err := msgpack.Marshal(u)
-		if err != nil {
-			return fmt.Errorf("encode message failed: %v", err)
-		}
+// appendHistory store updates and add their chats, historyMu should be locked
+func (b *Bot) appendHistory(updates []Update) error {
+	if b.history == nil {
+		return errHistoryClosed
+	}
 
-		b.historyFile.Write(line)
-		_, err = b.historyFile.Write([]byte("\n"))
-		if err != nil {
-			return fmt.Errorf("failed write to history: %v", err)
-		}
+	for _, u := range updates {
+		b.addChat(u)
 	}
 
-	if maxUpdateID > 0 {
-		b.UpdateID = maxUpdateID
+	return b.history.Append(updates)
+}
+
+// closeHistory close the history store
+func (b *Bot) closeHistory() error {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+
+	if b.history == nil {
+		return nil
 	}
 
-	return nil
+	err := b.history.Close()
+	b.history = nil
+
+	return err
 }
 
-func (b *Bot) loadUpdates() (updates []Update, err error) {
-	updates, err = b.GetUpdates(b.UpdateID + 1)
+func (b *Bot) loadUpdates(ctx context.Context) (updates []Update, err error) {
+	updates, err = b.getUpdates(ctx, b.UpdateID+1)
 	if err != nil {
 		return
 	}
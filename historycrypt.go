@@ -0,0 +1,283 @@
+package tebo
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// KeyProvider supply keys to encrypt records of the history by AES-GCM,
+// key must be 16, 24 or 32 bytes, new records are encrypted by the current key,
+// id of the key is stored in the record, so keys can be rotated
+type KeyProvider interface {
+	// CurrentKey return key to encrypt new records
+	CurrentKey() (id uint32, key []byte, err error)
+
+	// Key return key to decrypt records encrypted by it
+	Key(id uint32) ([]byte, error)
+}
+
+// StaticKeys is KeyProvider with fixed set of keys
+type StaticKeys struct {
+	Current uint32
+	Keys    map[uint32][]byte
+}
+
+func (k StaticKeys) CurrentKey() (uint32, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+func (k StaticKeys) Key(id uint32) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %d", id)
+	}
+
+	return key, nil
+}
+
+// WithHistoryKeys enable encryption of the history file created by NewBot
+func WithHistoryKeys(keys KeyProvider) Option {
+	return func(b *Bot) {
+		b.historyPolicy.Keys = keys
+	}
+}
+
+// ErrHistoryDecrypt is returned if encrypted record can not be decrypted
+// by the key, or keys are not set
+var ErrHistoryDecrypt = errors.New("history record can not be decrypted")
+
+// encryptedRecord is the first byte of encrypted record data, it is never used
+// by msgpack, so plain and encrypted records can be mixed in the same file
+const (
+	encryptedRecord     = 0xc1
+	encryptedHeaderSize = 5
+)
+
+// recordCodec encrypt and decrypt data of records, without keys data is not changed
+type recordCodec struct {
+	keys KeyProvider
+
+	mu    sync.Mutex
+	aeads map[uint32]cipher.AEAD
+}
+
+func newRecordCodec(keys KeyProvider) *recordCodec {
+	return &recordCodec{keys: keys, aeads: make(map[uint32]cipher.AEAD)}
+}
+
+// aead return cipher of the key, key is requested only if cipher is not cached
+func (c *recordCodec) aead(id uint32, key []byte) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if aead, ok := c.aeads[id]; ok {
+		return aead, nil
+	}
+
+	if key == nil {
+		var err error
+		if key, err = c.keys.Key(id); err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	c.aeads[id] = aead
+
+	return aead, nil
+}
+
+// seal encrypt data by the current key: marker, key id, nonce and ciphertext
+func (c *recordCodec) seal(data []byte) ([]byte, error) {
+	if c == nil || c.keys == nil {
+		return data, nil
+	}
+
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history key: %v", err)
+	}
+
+	aead, err := c.aead(id, key)
+	if err != nil {
+		return nil, fmt.Errorf("history key %d: %v", id, err)
+	}
+
+	out := make([]byte, encryptedHeaderSize+aead.NonceSize(), encryptedHeaderSize+aead.NonceSize()+len(data)+aead.Overhead())
+	out[0] = encryptedRecord
+	binary.BigEndian.PutUint32(out[1:encryptedHeaderSize], id)
+
+	nonce := out[encryptedHeaderSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// marker and key id are authenticated too
+	return aead.Seal(out, nonce, data, out[:encryptedHeaderSize]), nil
+}
+
+// open decrypt data of the encrypted record, plain data is returned as is
+func (c *recordCodec) open(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != encryptedRecord {
+		return data, nil
+	}
+
+	if c == nil || c.keys == nil {
+		return nil, fmt.Errorf("%w: record is encrypted, but keys are not set", ErrHistoryDecrypt)
+	}
+
+	if len(data) < encryptedHeaderSize {
+		return nil, fmt.Errorf("%w: record is too short", ErrHistoryDecrypt)
+	}
+
+	id := binary.BigEndian.Uint32(data[1:encryptedHeaderSize])
+
+	aead, err := c.aead(id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %d: %v", ErrHistoryDecrypt, id, err)
+	}
+
+	if len(data) < encryptedHeaderSize+aead.NonceSize() {
+		return nil, fmt.Errorf("%w: record is too short", ErrHistoryDecrypt)
+	}
+
+	nonce := data[encryptedHeaderSize : encryptedHeaderSize+aead.NonceSize()]
+	ciphertext := data[encryptedHeaderSize+aead.NonceSize():]
+
+	plain, err := aead.Open(nil, nonce, ciphertext, data[:encryptedHeaderSize])
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %d: %v", ErrHistoryDecrypt, id, err)
+	}
+
+	return plain, nil
+}
+
+// startsPlain return true if the first record of the file is not encrypted,
+// records are only appended and encrypted history can not be opened without keys,
+// so plain records of the file written before keys were set precede encrypted ones
+func startsPlain(filename, magic string) (bool, error) {
+	r, err := openHistoryFile(filename, magic)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	header := make([]byte, recordHeaderSize+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		// file without records
+		return false, nil
+	}
+
+	return header[recordHeaderSize] != encryptedRecord, nil
+}
+
+// encryptPlainHistory rewrite segments and the active file with plain records
+// encrypted, the snapshot with plain registry of chats is removed
+// and written again on closing
+func encryptPlainHistory(c *recordCodec, filename string) (converted bool, err error) {
+	segments, err := listSegments(filename)
+	if err != nil {
+		return false, err
+	}
+
+	paths := []string{filename}
+	for _, seg := range segments {
+		paths = append(paths, seg.path)
+	}
+
+	for _, path := range paths {
+		plain, err := startsPlain(path, historyMagic)
+		if err != nil {
+			return converted, err
+		}
+		if !plain {
+			continue
+		}
+
+		if err := c.encryptHistoryFile(path); err != nil {
+			return converted, fmt.Errorf("%s: %w", path, err)
+		}
+		converted = true
+	}
+
+	plain, err := startsPlain(filename+".snapshot", snapshotMagic)
+	if err != nil {
+		log.Warningf("history snapshot is not checked: %v", err)
+		plain = true
+	}
+
+	if converted || plain {
+		if err := os.Remove(filename + ".snapshot"); err != nil && !os.IsNotExist(err) {
+			return converted, err
+		}
+	}
+
+	if converted {
+		log.Info("plain records of the history are encrypted")
+	}
+
+	return converted, nil
+}
+
+// encryptHistoryFile rewrite all records of the file by the current key,
+// modification time is kept, since it is age of updates without date
+func (c *recordCodec) encryptHistoryFile(filename string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	r, err := openHistoryFile(filename, historyMagic)
+	if err != nil {
+		return err
+	}
+
+	var updates []Update
+	_, err = c.readRecords(bufio.NewReader(r), int64(historyHeaderSize), func(u Update) bool {
+		updates = append(updates, u)
+		return true
+	})
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	err = writeHistoryFile(filename, historyMagic, func(w io.Writer) error {
+		for _, u := range updates {
+			record, err := c.encode(u)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.Chtimes(filename, fi.ModTime(), fi.ModTime())
+}
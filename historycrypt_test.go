@@ -0,0 +1,140 @@
+package tebo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestFileHistoryEncryption(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+
+	key1 := bytes.Repeat([]byte{1}, 32)
+	key2 := bytes.Repeat([]byte{2}, 32)
+
+	store, err := NewFileHistory(filename, HistoryPolicy{Keys: StaticKeys{Current: 1, Keys: map[uint32][]byte{1: key1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Append([]Update{textUpdate(1, 10, "secret message")})
+	store.Close()
+
+	data, _ := os.ReadFile(filename)
+	if bytes.Contains(data, []byte("secret message")) {
+		t.Errorf("history is not encrypted")
+	}
+
+	if fi, _ := os.Stat(filename); fi.Mode().Perm() != 0600 {
+		t.Errorf("history is accessible by others: %v", fi.Mode())
+	}
+
+	// key is rotated, old records are read by the previous key
+	keys := StaticKeys{Current: 2, Keys: map[uint32][]byte{1: key1, 2: key2}}
+	store, err = NewFileHistory(filename, HistoryPolicy{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Append([]Update{textUpdate(2, 10, "another secret")})
+
+	updates := readAll(t, store)
+	if len(updates) != 2 || updates[0].Message.Text != "secret message" || updates[1].Message.Text != "another secret" {
+		t.Errorf("unexpected decrypted updates %+v", updates)
+	}
+	store.Close()
+
+	// without the key history can not be read
+	for _, policy := range []HistoryPolicy{
+		{},
+		{Keys: StaticKeys{Current: 2, Keys: map[uint32][]byte{2: key2}}},
+		{Keys: StaticKeys{Current: 1, Keys: map[uint32][]byte{1: key2, 2: key2}}},
+	} {
+		os.Remove(filename + ".snapshot")
+
+		store, err := NewFileHistory(filename, policy)
+		if err == nil {
+			store.Close()
+		}
+
+		var herr *HistoryError
+		if !errors.Is(err, ErrHistoryDecrypt) || !errors.As(err, &herr) {
+			t.Errorf("expected decryption error, got %v", err)
+		}
+	}
+}
+
+func TestFileHistoryEncryptPlain(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "history")
+
+	// history written before keys are set, with rotated segments
+	store, err := NewFileHistory(filename, HistoryPolicy{SegmentSize: 64, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		store.Append([]Update{textUpdate(i, 10, "plain secret")})
+	}
+	store.Close()
+
+	keys := StaticKeys{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}
+
+	store, err = NewFileHistory(filename, HistoryPolicy{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updates := readAll(t, store); len(updates) != 4 {
+		t.Errorf("expected 4 updates after encryption, got %d", len(updates))
+	}
+	store.Close()
+
+	paths, _ := filepath.Glob(filename + "*")
+	if len(paths) < 3 {
+		t.Fatalf("segments are not rotated: %v", paths)
+	}
+
+	for _, path := range paths {
+		plain := false
+		if strings.HasSuffix(path, ".snapshot") {
+			plain, err = startsPlain(path, snapshotMagic)
+		} else {
+			plain, err = startsPlain(path, historyMagic)
+		}
+		if err != nil || plain {
+			t.Errorf("%s: plain records are left: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestFileHistoryLegacyEncrypted(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+
+	line, err := msgpack.Marshal(textUpdate(1, 10, "plain secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, append(line, '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := StaticKeys{Current: 1, Keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}
+
+	store, err := NewFileHistory(filename, HistoryPolicy{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if updates := readAll(t, store); len(updates) != 1 {
+		t.Errorf("legacy history is not converted, %d updates", len(updates))
+	}
+
+	if _, err := os.Stat(filename + ".old"); !os.IsNotExist(err) {
+		t.Errorf("plain copy of legacy history is kept: %v", err)
+	}
+}
@@ -0,0 +1,1076 @@
+package tebo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// History file starts with header: magic and version of the format,
+// then records follow: length and CRC32 of the data, and the data itself
+const (
+	historyMagic   = "TEBOHIS"
+	historyVersion = 1
+
+	historyHeaderSize = len(historyMagic) + 1
+	recordHeaderSize  = 8
+
+	// maxRecordSize protect from allocation of huge buffer for corrupted length
+	maxRecordSize = 64 << 20
+)
+
+// ErrHistoryCorrupted is returned if history record can not be read
+var ErrHistoryCorrupted = errors.New("history is corrupted")
+
+// HistoryError describes failure to read the record of the history file
+type HistoryError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *HistoryError) Error() string {
+	return fmt.Sprintf("history record at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *HistoryError) Unwrap() error {
+	return e.Err
+}
+
+// checkHistoryHeader write header to the new file, return true if file has the old format
+func checkHistoryHeader(f *os.File) (legacy bool, err error) {
+	header := make([]byte, historyHeaderSize)
+
+	n, err := f.ReadAt(header, 0)
+	if n == 0 && err == io.EOF {
+		_, err = f.Write(newHistoryHeader())
+		return false, err
+	}
+
+	if string(header[:len(historyMagic)]) != historyMagic {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, &HistoryError{Err: fmt.Errorf("%w: incomplete header", ErrHistoryCorrupted)}
+	}
+
+	if v := header[len(historyMagic)]; v != historyVersion {
+		return false, fmt.Errorf("unsupported version of history file %d", v)
+	}
+
+	return false, nil
+}
+
+func newHistoryHeader() []byte {
+	return append([]byte(historyMagic), historyVersion)
+}
+
+// writeHistoryFile write records to the new file through temporary file,
+// the file is compressed if its name has .gz suffix
+func writeHistoryFile(filename, magic string, records func(w io.Writer) error) error {
+	tmp, err := os.OpenFile(filename+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriter(tmp)
+
+	var w io.Writer = bw
+	var gz *gzip.Writer
+	if strings.HasSuffix(filename, ".gz") {
+		gz = gzip.NewWriter(bw)
+		w = gz
+	}
+
+	_, err = w.Write(append([]byte(magic), historyVersion))
+	if err == nil {
+		err = records(w)
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// openHistoryFile open file of records for reading, compressed file is unpacked
+func openHistoryFile(filename, magic string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.ReadCloser = f
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			f.Close()
+			return nil, &HistoryError{Err: fmt.Errorf("%w: %v", ErrHistoryCorrupted, err)}
+		}
+		r = struct {
+			io.Reader
+			io.Closer
+		}{gz, f}
+	}
+
+	if err := readHistoryHeader(r, magic); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return r, nil
+}
+
+// truncateHistoryTail cut incomplete or broken last record left by interrupted write,
+// otherwise following records would be unreadable, broken record followed
+// by valid records is not left by interrupted write and is returned as HistoryError
+func truncateHistoryTail(f *os.File) error {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	offset := int64(historyHeaderSize)
+	for offset < size {
+		n, ok, err := checkRecord(f, offset, size)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		offset += n
+	}
+
+	if offset == size {
+		return nil
+	}
+
+	next, found, err := findRecord(f, offset, size)
+	if err != nil {
+		return err
+	}
+	if found {
+		return &HistoryError{Offset: offset, Err: fmt.Errorf("%w: broken record is followed by record at offset %d", ErrHistoryCorrupted, next)}
+	}
+
+	log.Warningf("history has incomplete record at offset %d, %d bytes are cut", offset, size-offset)
+
+	return f.Truncate(offset)
+}
+
+// checkRecord return size of the record at the offset,
+// false if the record is incomplete or its checksum does not match
+func checkRecord(r io.ReaderAt, offset, size int64) (int64, bool, error) {
+	if size-offset < recordHeaderSize {
+		return 0, false, nil
+	}
+
+	header := make([]byte, recordHeaderSize)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return 0, false, err
+	}
+
+	// records are never empty, so zeros left by interrupted write are not records
+	length := int64(binary.BigEndian.Uint32(header))
+	if length == 0 || length > maxRecordSize || offset+recordHeaderSize+length > size {
+		return 0, false, nil
+	}
+
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset+recordHeaderSize); err != nil {
+		return 0, false, err
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, false, nil
+	}
+
+	return recordHeaderSize + length, true, nil
+}
+
+// findRecord search valid record after the broken record at the offset
+func findRecord(f *os.File, offset, size int64) (int64, bool, error) {
+	rest := make([]byte, size-offset)
+	if _, err := f.ReadAt(rest, offset); err != nil {
+		return 0, false, err
+	}
+
+	r := bytes.NewReader(rest)
+	for i := int64(1); i+recordHeaderSize < int64(len(rest)); i++ {
+		if _, ok, err := checkRecord(r, i, int64(len(rest))); err != nil || ok {
+			return offset + i, ok, err
+		}
+	}
+
+	return 0, false, nil
+}
+
+// encode return record of the value with length and checksum,
+// data is encrypted if keys are set
+func (c *recordCodec) encode(v interface{}) ([]byte, error) {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode message failed: %v", err)
+	}
+
+	if data, err = c.seal(data); err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+
+	return append(record, data...), nil
+}
+
+// readRecords pass records of updates until f returns false,
+// offset is position of the first record, return position after the last record
+func (c *recordCodec) readRecords(r io.Reader, offset int64, f func(Update) bool) (int64, error) {
+	for {
+		var u Update
+		n, err := c.read(r, offset, &u)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		offset += n
+
+		if !f(u) {
+			return offset, nil
+		}
+	}
+}
+
+// readHistoryHeader check header of the file read from the beginning
+func readHistoryHeader(r io.Reader, magic string) error {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return &HistoryError{Err: fmt.Errorf("%w: incomplete header", ErrHistoryCorrupted)}
+	}
+
+	if string(header[:len(magic)]) != magic {
+		return &HistoryError{Err: fmt.Errorf("%w: unknown header", ErrHistoryCorrupted)}
+	}
+
+	if v := header[len(magic)]; v != historyVersion {
+		return fmt.Errorf("unsupported version of history file %d", v)
+	}
+
+	return nil
+}
+
+// read decode the next record to v, return io.EOF at the end of records
+func (c *recordCodec) read(r io.Reader, offset int64, v interface{}) (size int64, err error) {
+	header := make([]byte, recordHeaderSize)
+
+	if _, err = io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = &HistoryError{Offset: offset, Err: fmt.Errorf("%w: incomplete record", ErrHistoryCorrupted)}
+		}
+		return
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		err = &HistoryError{Offset: offset, Err: fmt.Errorf("%w: record length %d", ErrHistoryCorrupted, length)}
+		return
+	}
+
+	data := make([]byte, length)
+	if _, err = io.ReadFull(r, data); err != nil {
+		err = &HistoryError{Offset: offset, Err: fmt.Errorf("%w: incomplete record", ErrHistoryCorrupted)}
+		return
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		err = &HistoryError{Offset: offset, Err: fmt.Errorf("%w: checksum mismatch", ErrHistoryCorrupted)}
+		return
+	}
+
+	if data, err = c.open(data); err != nil {
+		err = &HistoryError{Offset: offset, Err: err}
+		return
+	}
+
+	if err = msgpack.Unmarshal(data, v); err != nil {
+		err = &HistoryError{Offset: offset, Err: fmt.Errorf("%w: %v", ErrHistoryCorrupted, err)}
+		return
+	}
+
+	return recordHeaderSize + int64(length), nil
+}
+
+// convertLegacyHistory rewrite file of msgpack records separated by newline,
+// records are read by stream decoder, since data may contain newlines,
+// it stops on the first broken record
+func convertLegacyHistory(c *recordCodec, filename string) error {
+	old, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	var count int
+	err = writeHistoryFile(filename+".new", historyMagic, func(w io.Writer) error {
+		r := bufio.NewReader(old)
+		dec := msgpack.NewDecoder(r)
+
+		for {
+			var u Update
+			if err := dec.Decode(&u); err != nil {
+				if err != io.EOF {
+					log.Warningf("history of the old format is broken after %d records: %v", count, err)
+				}
+				return nil
+			}
+
+			if b, err := r.ReadByte(); err != nil || b != '\n' {
+				log.Warningf("history of the old format is broken after %d records: no separator", count+1)
+				return nil
+			}
+
+			record, err := c.encode(u)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(record); err != nil {
+				return err
+			}
+			count++
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename + ".new")
+
+	// plain copy of encrypted history is not kept
+	if c.keys != nil {
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+	} else if err := os.Rename(filename, filename+".old"); err != nil {
+		return err
+	}
+
+	log.Infof("history is converted to the new format, %d records", count)
+
+	return os.Rename(filename+".new", filename)
+}
+
+const snapshotMagic = "TEBOSNP"
+
+// HistoryPolicy control size of the history file: active file is rotated
+// into numbered segments, segments are compacted by retention rules,
+// zero values disable the rules
+type HistoryPolicy struct {
+	// SegmentSize is size of the active file to rotate it
+	SegmentSize int64
+
+	// Compress rotated segments by gzip
+	Compress bool
+
+	// MaxAge of stored updates
+	MaxAge time.Duration
+
+	// MaxSize of all segments and the active file,
+	// the oldest segments are removed to fit it
+	MaxSize int64
+
+	// MaxRecordsPerChat is number of the last updates kept for each chat
+	MaxRecordsPerChat int
+
+	// CompactInterval is period of compaction, else it is called by Compact
+	CompactInterval time.Duration
+
+	// Keys encrypt records, if it is set
+	Keys KeyProvider
+}
+
+// WithHistoryPolicy set rotation and retention of the history file created by NewBot
+func WithHistoryPolicy(p HistoryPolicy) Option {
+	return func(b *Bot) {
+		b.historyPolicy = p
+	}
+}
+
+// historySnapshot is the registry of chats at the position of the active file,
+// segment is number of the active file
+type historySnapshot struct {
+	Segment int           `msgpack:"segment"`
+	Offset  int64         `msgpack:"offset"`
+	State   *historyState `msgpack:"state"`
+}
+
+type historySegment struct {
+	num  int
+	path string
+}
+
+// FileHistory store updates in the file as msgpack records with length and checksum,
+// registry of chats is saved to snapshot on rotation and closing, so on startup
+// only updates after the snapshot are read
+type FileHistory struct {
+	filename string
+	policy   HistoryPolicy
+
+	// segMu guard rotated segments, it is locked before mu
+	segMu    sync.RWMutex
+	segments []historySegment
+
+	codec *recordCodec
+
+	mu    sync.Mutex
+	file  *os.File
+	size  int64
+	state *historyState
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFileHistory open the history file, file of the old format with records separated
+// by newline is converted, the old file is kept with .old suffix unless keys are set,
+// plain records are encrypted when keys are set for the existing history
+func NewFileHistory(filename string, policy ...HistoryPolicy) (*FileHistory, error) {
+	h := &FileHistory{filename: filename}
+	if len(policy) > 0 {
+		h.policy = policy[0]
+	}
+	h.codec = newRecordCodec(h.policy.Keys)
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	// file of previous versions is created readable by everyone
+	if err := f.Chmod(0600); err != nil {
+		log.Warningf("failed to restrict access to history: %v", err)
+	}
+
+	legacy, err := checkHistoryHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if legacy {
+		f.Close()
+
+		if err := convertLegacyHistory(h.codec, filename); err != nil {
+			return nil, fmt.Errorf("failed to convert history of the old format: %v", err)
+		}
+
+		return NewFileHistory(filename, policy...)
+	}
+
+	if err := truncateHistoryTail(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// records written before keys were set are encrypted
+	if h.policy.Keys != nil {
+		converted, err := encryptPlainHistory(h.codec, filename)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to encrypt history: %v", err)
+		}
+
+		if converted {
+			f.Close()
+			return NewFileHistory(filename, policy...)
+		}
+	}
+
+	h.file = f
+
+	if err := h.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if h.policy.CompactInterval > 0 {
+		h.stop = make(chan struct{})
+		h.done = make(chan struct{})
+		go h.compactor()
+	}
+
+	return h, nil
+}
+
+// load restore registry from the snapshot and the following updates,
+// without valid snapshot all segments are read
+func (h *FileHistory) load() (err error) {
+	if h.size, err = h.file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	if h.segments, err = listSegments(h.filename); err != nil {
+		return err
+	}
+
+	offset := int64(historyHeaderSize)
+
+	snap, err := h.readSnapshot(h.filename + ".snapshot")
+	if err == nil && snap.Segment == h.activeSegment() && snap.Offset <= h.size && snap.State != nil {
+		h.state = snap.State
+		h.state.reindex()
+		offset = snap.Offset
+	} else {
+		if err != nil && !os.IsNotExist(err) {
+			log.Warningf("history snapshot is not used: %v", err)
+		}
+
+		h.state = newHistoryState()
+		for _, seg := range h.segments {
+			if err := h.rangeSegment(seg.path, func(u Update) bool {
+				h.state.add(u)
+				return true
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(h.file, offset, h.size-offset))
+	_, err = h.codec.readRecords(r, offset, func(u Update) bool {
+		h.state.add(u)
+		return true
+	})
+
+	return err
+}
+
+func (h *FileHistory) Append(updates []Update) error {
+	h.mu.Lock()
+
+	if h.file == nil {
+		h.mu.Unlock()
+		return errHistoryClosed
+	}
+
+	var buf bytes.Buffer
+	for _, u := range updates {
+		record, err := h.codec.encode(u)
+		if err != nil {
+			h.mu.Unlock()
+			return err
+		}
+		buf.Write(record)
+	}
+
+	// updates are written at once to not leave partial batch
+	n, err := h.file.Write(buf.Bytes())
+	h.size += int64(n)
+	if err != nil {
+		h.mu.Unlock()
+		return fmt.Errorf("failed write to history: %v", err)
+	}
+
+	for _, u := range updates {
+		h.state.add(u)
+	}
+
+	rotate := h.policy.SegmentSize > 0 && h.size >= h.policy.SegmentSize
+	h.mu.Unlock()
+
+	if !rotate {
+		return nil
+	}
+
+	h.segMu.Lock()
+	defer h.segMu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// file could be rotated by concurrent append
+	if h.file == nil || h.size < h.policy.SegmentSize {
+		return nil
+	}
+
+	return h.rotate()
+}
+
+func (h *FileHistory) LastUpdateID() (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state.LastUpdateID, nil
+}
+
+// Range pass stored updates of segments and the active file,
+// it stops on the first broken record and return HistoryError
+func (h *FileHistory) Range(f func(Update) bool) error {
+	h.segMu.RLock()
+	defer h.segMu.RUnlock()
+
+	stopped := false
+	next := func(u Update) bool {
+		stopped = !f(u)
+		return !stopped
+	}
+
+	for _, seg := range h.segments {
+		if err := h.rangeSegment(seg.path, next); err != nil || stopped {
+			return err
+		}
+	}
+
+	// active file is not rotated while segments are locked
+	h.mu.Lock()
+	file, size := h.file, h.size
+	h.mu.Unlock()
+
+	if file == nil {
+		return errHistoryClosed
+	}
+
+	offset := int64(historyHeaderSize)
+	r := bufio.NewReader(io.NewSectionReader(file, offset, size-offset))
+	_, err := h.codec.readRecords(r, offset, next)
+
+	return err
+}
+
+func (h *FileHistory) Chats(f func(Chat) bool) error {
+	h.mu.Lock()
+	chats := h.state.Chats[:len(h.state.Chats):len(h.state.Chats)]
+	h.mu.Unlock()
+
+	for _, chat := range chats {
+		if !f(chat) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (h *FileHistory) Migrations(f func(from, to int) bool) error {
+	h.mu.Lock()
+	migrations := make(map[int]int, len(h.state.Migrations))
+	for from, to := range h.state.Migrations {
+		migrations[from] = to
+	}
+	h.mu.Unlock()
+
+	for from, to := range migrations {
+		if !f(from, to) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Close save snapshot, flush and close the history file
+func (h *FileHistory) Close() error {
+	if h.stop != nil {
+		select {
+		case <-h.stop:
+		default:
+			close(h.stop)
+		}
+		<-h.done
+	}
+
+	h.segMu.Lock()
+	defer h.segMu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.file == nil {
+		return nil
+	}
+
+	err := h.writeSnapshot()
+	if e := h.file.Sync(); err == nil {
+		err = e
+	}
+	if e := h.file.Close(); err == nil {
+		err = e
+	}
+
+	h.file = nil
+
+	return err
+}
+
+//
+// Segments
+//
+
+// listSegments find rotated segments of the history file ordered by number
+func listSegments(filename string) ([]historySegment, error) {
+	paths, err := filepath.Glob(filename + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []historySegment
+	for _, path := range paths {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(path, filename+"."), ".gz")
+
+		num, err := strconv.Atoi(suffix)
+		if err != nil || num <= 0 {
+			continue
+		}
+
+		segments = append(segments, historySegment{num: num, path: path})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].num < segments[j].num
+	})
+
+	return segments, nil
+}
+
+func (h *FileHistory) rangeSegment(path string, f func(Update) bool) error {
+	r, err := openHistoryFile(path, historyMagic)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = h.codec.readRecords(bufio.NewReader(r), int64(historyHeaderSize), f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// activeSegment return number which the active file will have after rotation
+func (h *FileHistory) activeSegment() int {
+	if len(h.segments) == 0 {
+		return 1
+	}
+
+	return h.segments[len(h.segments)-1].num + 1
+}
+
+// rotate move active file to the new segment and save snapshot,
+// both segMu and mu should be locked
+func (h *FileHistory) rotate() error {
+	if h.size <= int64(historyHeaderSize) {
+		return nil
+	}
+
+	seg := historySegment{num: h.activeSegment()}
+	seg.path = fmt.Sprintf("%s.%06d", h.filename, seg.num)
+
+	if err := h.file.Sync(); err != nil {
+		return err
+	}
+
+	if h.policy.Compress {
+		seg.path += ".gz"
+
+		size := h.size
+		err := writeHistoryFile(seg.path, historyMagic, func(w io.Writer) error {
+			_, err := io.Copy(w, io.NewSectionReader(h.file, int64(historyHeaderSize), size-int64(historyHeaderSize)))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		h.file.Close()
+	} else {
+		h.file.Close()
+
+		if err := os.Rename(h.filename, seg.path); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(h.filename, os.O_CREATE|os.O_TRUNC|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		h.file = nil
+		return err
+	}
+
+	if _, err := f.Write(newHistoryHeader()); err != nil {
+		f.Close()
+		h.file = nil
+		return err
+	}
+
+	h.file = f
+	h.size = int64(historyHeaderSize)
+	h.segments = append(h.segments, seg)
+
+	return h.writeSnapshot()
+}
+
+//
+// Snapshot
+//
+
+// writeSnapshot save registry at the current position of the active file,
+// segMu and mu should be locked
+func (h *FileHistory) writeSnapshot() error {
+	snap := historySnapshot{
+		Segment: h.activeSegment(),
+		Offset:  h.size,
+		State:   h.state,
+	}
+
+	record, err := h.codec.encode(snap)
+	if err != nil {
+		return err
+	}
+
+	return writeHistoryFile(h.filename+".snapshot", snapshotMagic, func(w io.Writer) error {
+		_, err := w.Write(record)
+		return err
+	})
+}
+
+func (h *FileHistory) readSnapshot(filename string) (snap historySnapshot, err error) {
+	r, err := openHistoryFile(filename, snapshotMagic)
+	if err != nil {
+		return snap, err
+	}
+	defer r.Close()
+
+	_, err = h.codec.read(r, int64(historyHeaderSize), &snap)
+	return snap, err
+}
+
+//
+// Compaction
+//
+
+func (h *FileHistory) compactor() {
+	defer close(h.done)
+
+	t := time.NewTicker(h.policy.CompactInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-t.C:
+			if err := h.Compact(); err != nil {
+				log.Errorf("history compaction failed: %v", err)
+			}
+		}
+	}
+}
+
+// Compact rotate the active file, apply retention rules to segments
+// and save snapshot, updates can be appended while segments are compacted
+func (h *FileHistory) Compact() error {
+	h.segMu.Lock()
+	defer h.segMu.Unlock()
+
+	h.mu.Lock()
+	if h.file == nil {
+		h.mu.Unlock()
+		return errHistoryClosed
+	}
+	err := h.rotate()
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := h.retain(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.writeSnapshot()
+}
+
+// retain remove updates from segments by retention rules, segMu should be locked
+func (h *FileHistory) retain() error {
+	p := h.policy
+
+	// number of the oldest updates of each chat to remove
+	drop := make(map[int]int)
+	if p.MaxRecordsPerChat > 0 {
+		counts := make(map[int]int)
+		for _, seg := range h.segments {
+			if err := h.rangeSegment(seg.path, func(u Update) bool {
+				counts[updateKey(u)]++
+				return true
+			}); err != nil {
+				return err
+			}
+		}
+
+		// updates of the active file are kept, but counted
+		h.mu.Lock()
+		file, size := h.file, h.size
+		h.mu.Unlock()
+
+		offset := int64(historyHeaderSize)
+		r := bufio.NewReader(io.NewSectionReader(file, offset, size-offset))
+		if _, err := h.codec.readRecords(r, offset, func(u Update) bool {
+			counts[updateKey(u)]++
+			return true
+		}); err != nil {
+			return err
+		}
+
+		for key, n := range counts {
+			if n > p.MaxRecordsPerChat {
+				drop[key] = n - p.MaxRecordsPerChat
+			}
+		}
+	}
+
+	if p.MaxAge > 0 || len(drop) > 0 {
+		deadline := time.Now().Add(-p.MaxAge).Unix()
+
+		var segments []historySegment
+		for _, seg := range h.segments {
+			keep, err := h.filterSegment(seg, func(u Update, modtime int64) bool {
+				if key := updateKey(u); drop[key] > 0 {
+					drop[key]--
+					return false
+				}
+
+				if p.MaxAge <= 0 {
+					return true
+				}
+
+				// updates without date are removed with their segment
+				date := updateDate(u)
+				if date == 0 {
+					date = modtime
+				}
+
+				return date >= deadline
+			})
+			if err != nil {
+				return err
+			}
+
+			if keep {
+				segments = append(segments, seg)
+			}
+		}
+
+		h.segments = segments
+	}
+
+	if p.MaxSize > 0 {
+		h.mu.Lock()
+		total := h.size
+		h.mu.Unlock()
+
+		sizes := make([]int64, len(h.segments))
+		for i, seg := range h.segments {
+			if fi, err := os.Stat(seg.path); err == nil {
+				sizes[i] = fi.Size()
+				total += sizes[i]
+			}
+		}
+
+		var removed int
+		for removed < len(h.segments) && total > p.MaxSize {
+			if err := os.Remove(h.segments[removed].path); err != nil {
+				return err
+			}
+
+			total -= sizes[removed]
+			removed++
+		}
+
+		h.segments = h.segments[removed:]
+	}
+
+	return nil
+}
+
+// filterSegment rewrite segment with updates accepted by keep,
+// segment without updates is removed, return false if it is removed
+func (h *FileHistory) filterSegment(seg historySegment, keep func(u Update, modtime int64) bool) (bool, error) {
+	fi, err := os.Stat(seg.path)
+	if err != nil {
+		return false, err
+	}
+	modtime := fi.ModTime()
+
+	var kept []Update
+	var total int
+	if err := h.rangeSegment(seg.path, func(u Update) bool {
+		total++
+		if keep(u, modtime.Unix()) {
+			kept = append(kept, u)
+		}
+		return true
+	}); err != nil {
+		return false, err
+	}
+
+	if len(kept) == total {
+		return true, nil
+	}
+
+	if len(kept) == 0 {
+		return false, os.Remove(seg.path)
+	}
+
+	err = writeHistoryFile(seg.path, historyMagic, func(w io.Writer) error {
+		for _, u := range kept {
+			record, err := h.codec.encode(u)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// age of updates without date is age of the segment
+	return true, os.Chtimes(seg.path, modtime, modtime)
+}
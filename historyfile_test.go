@@ -0,0 +1,246 @@
+package tebo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func readAll(t *testing.T, store HistoryStore) (updates []Update) {
+	err := store.Range(func(u Update) bool {
+		updates = append(updates, u)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestFileHistoryLegacy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+
+	// chat id 10 is encoded as newline byte, long text exceeds limit of the scanner
+	legacy := []Update{
+		textUpdate(1, 10, "a"),
+		textUpdate(2, 20, strings.Repeat("x", 100<<10)),
+		textUpdate(3, 10, "c"),
+	}
+
+	var data []byte
+	for _, u := range legacy {
+		line, err := msgpack.Marshal(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	updates := readAll(t, store)
+	if len(updates) != 3 || updates[0].Message.Chat.ID != 10 || len(updates[1].Message.Text) != 100<<10 {
+		t.Errorf("legacy history is not converted, %d updates", len(updates))
+	}
+
+	if _, err := os.Stat(filename + ".old"); err != nil {
+		t.Errorf("legacy history is not kept: %v", err)
+	}
+}
+
+func TestFileHistoryCorrupted(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+
+	store, err := NewFileHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Append([]Update{textUpdate(1, 10, "a"), textUpdate(2, 10, "b")})
+	store.Close()
+
+	data, _ := os.ReadFile(filename)
+
+	// damage data of the first record
+	damaged := append([]byte(nil), data...)
+	damaged[historyHeaderSize+recordHeaderSize+2] ^= 0xff
+	os.WriteFile(filename, damaged, 0600)
+
+	// broken record followed by valid records is not cut
+	store, err = NewFileHistory(filename)
+	if err == nil {
+		store.Close()
+	}
+
+	var herr *HistoryError
+	if !errors.Is(err, ErrHistoryCorrupted) || !errors.As(err, &herr) || herr.Offset != int64(historyHeaderSize) {
+		t.Errorf("expected corrupted error of the first record, got %v", err)
+	}
+
+	// damage length of the first record, so it points past the end of the file
+	copy(damaged, data)
+	binary.BigEndian.PutUint32(damaged[historyHeaderSize:], uint32(len(data)))
+	os.WriteFile(filename, damaged, 0600)
+
+	if _, err := NewFileHistory(filename); !errors.Is(err, ErrHistoryCorrupted) {
+		t.Errorf("expected corrupted error of the record length, got %v", err)
+	}
+	if fi, _ := os.Stat(filename); fi.Size() != int64(len(data)) {
+		t.Errorf("records after the broken length are cut, size %d", fi.Size())
+	}
+
+	// broken last record is cut on opening
+	copy(damaged, data)
+	damaged[len(damaged)-2] ^= 0xff
+	os.WriteFile(filename, damaged, 0600)
+	os.Remove(filename + ".snapshot")
+
+	store, err = NewFileHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updates := readAll(t, store); len(updates) != 1 {
+		t.Errorf("broken last record is not cut, %d updates", len(updates))
+	}
+	store.Close()
+
+	// incomplete record is cut on opening
+	os.WriteFile(filename, data[:len(data)-3], 0600)
+
+	store, err = NewFileHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	store.Append([]Update{textUpdate(3, 10, "c")})
+
+	updates := readAll(t, store)
+	if len(updates) != 2 || updates[0].UpdateID != 1 || updates[1].UpdateID != 3 {
+		t.Errorf("unexpected updates after cutting incomplete record: %d", len(updates))
+	}
+}
+
+func datedUpdate(id, chatid int, date time.Time) Update {
+	u := textUpdate(id, chatid, "text")
+	u.Message.Date = date.Unix()
+	return u
+}
+
+func TestFileHistoryRotation(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		filename := filepath.Join(t.TempDir(), "history")
+
+		store, err := NewFileHistory(filename, HistoryPolicy{SegmentSize: 256, Compress: compress})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 1; i <= 20; i++ {
+			if err := store.Append([]Update{textUpdate(i, i%3+1, "some text")}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		store.Close()
+
+		segments, _ := listSegments(filename)
+		if len(segments) < 2 {
+			t.Fatalf("history is not rotated, segments %v", segments)
+		}
+		if compress != strings.HasSuffix(segments[0].path, ".gz") {
+			t.Errorf("unexpected segment %s", segments[0].path)
+		}
+
+		store, err = NewFileHistory(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if updates := readAll(t, store); len(updates) != 20 || updates[19].UpdateID != 20 {
+			t.Errorf("unexpected updates after rotation: %d", len(updates))
+		}
+		store.Close()
+
+		// registry is restored from snapshot without reading of segments
+		os.WriteFile(segments[0].path, []byte("broken"), 0600)
+
+		store, err = NewFileHistory(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var chats int
+		store.Chats(func(Chat) bool { chats++; return true })
+
+		if id, _ := store.LastUpdateID(); id != 20 || chats != 3 {
+			t.Errorf("unexpected state from snapshot: last update %d, chats %d", id, chats)
+		}
+		store.Close()
+	}
+}
+
+func TestFileHistoryRetention(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+
+	store, err := NewFileHistory(filename, HistoryPolicy{MaxAge: time.Hour, MaxRecordsPerChat: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	store.Append([]Update{
+		datedUpdate(1, 20, now.Add(-2*time.Hour)),
+		datedUpdate(2, 10, now),
+		datedUpdate(3, 10, now),
+		datedUpdate(4, 10, now),
+		datedUpdate(5, 30, now),
+	})
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, u := range readAll(t, store) {
+		ids = append(ids, u.UpdateID)
+	}
+
+	if fmt.Sprint(ids) != "[3 4 5]" {
+		t.Errorf("unexpected updates after compaction %v", ids)
+	}
+
+	// chats of removed updates are kept
+	var chats int
+	store.Chats(func(Chat) bool { chats++; return true })
+	if chats != 3 {
+		t.Errorf("unexpected number of chats %d", chats)
+	}
+
+	// the oldest segments are removed to fit size
+	store.policy = HistoryPolicy{MaxSize: 1}
+	store.Append([]Update{datedUpdate(6, 10, now)})
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if updates := readAll(t, store); len(updates) != 0 {
+		t.Errorf("segments are not removed by size, %d updates", len(updates))
+	}
+}
@@ -0,0 +1,321 @@
+package tebo
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InlineFunc handle inline query and return results shown to the user
+type InlineFunc func(*InlineContext) []InlineQueryResult
+
+type inlineHandler struct {
+	pattern  string
+	exp      *regexp.Regexp
+	callback InlineFunc
+}
+
+// HandleInline register handler for inline queries matched by the pattern,
+// results returned by handler are sent by AnswerInlineQuery, inline queries
+// not matched by any pattern are passed to the handler registered by OnInlineQuery
+func (b *Bot) HandleInline(pattern string, f InlineFunc) error {
+	exp, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return err
+	}
+
+	b.inlineHandlers = append(b.inlineHandlers, inlineHandler{
+		pattern:  pattern,
+		exp:      exp,
+		callback: f,
+	})
+
+	return nil
+}
+
+// InlineContext argument for inline query handlers,
+// answer parameters can be changed by handler
+type InlineContext struct {
+	*Context
+
+	Query *InlineQuery
+
+	// Matches of the pattern in the query text
+	Matches []string
+
+	CacheTime  int
+	IsPersonal bool
+	NextOffset string
+	Button     *InlineQueryResultsButton
+}
+
+// Offset return offset of the requested page, it is zero for the first page
+func (ctx *InlineContext) Offset() int {
+	offset, _ := strconv.Atoi(ctx.Query.Offset)
+	return offset
+}
+
+// Page return results of the requested page and set offset of the next one,
+// telegram allows no more than 50 results per answer
+func (ctx *InlineContext) Page(results []InlineQueryResult, size int) []InlineQueryResult {
+	offset := ctx.Offset()
+	if offset >= len(results) {
+		ctx.NextOffset = ""
+		return nil
+	}
+
+	end := offset + size
+	if end >= len(results) {
+		ctx.NextOffset = ""
+		end = len(results)
+	} else {
+		ctx.NextOffset = strconv.Itoa(end)
+	}
+
+	return results[offset:end]
+}
+
+// routeInline pass inline query to the first matched handler and answer it,
+// return false if no handler is matched
+func (b *Bot) routeInline(ctx *Context) bool {
+	q := ctx.InlineQuery
+
+	for _, ih := range b.inlineHandlers {
+		matches := ih.exp.FindStringSubmatch(q.Query)
+		if matches == nil {
+			continue
+		}
+
+		ictx := &InlineContext{Context: ctx, Query: q, Matches: matches}
+
+		var results []InlineQueryResult
+		var handled bool
+
+		// handler is wrapped to pass it through middlewares
+		h := handler{cmd: ih.pattern, exp: ih.exp, callback: func(*Context) *SendMessage {
+			results = ih.callback(ictx)
+			handled = true
+			return nil
+		}}
+
+		if err := b.execute(ctx, h); err != nil {
+			log.Errorf("failed to handle inline query: %v", err)
+		}
+
+		if !handled {
+			return true
+		}
+
+		err := b.AnswerInlineQuery(ReqAnswerInlineQuery{
+			InlineQueryID: q.ID,
+			Results:       results,
+			CacheTime:     ictx.CacheTime,
+			IsPersonal:    ictx.IsPersonal,
+			NextOffset:    ictx.NextOffset,
+			Button:        ictx.Button,
+		})
+		if err != nil {
+			log.Errorf("failed to answer inline query: %v", err)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+type InlineQueryResultsButton struct {
+	Text           string `json:"text"`
+	StartParameter string `json:"start_parameter,omitempty"`
+}
+
+type ReqAnswerInlineQuery struct {
+	InlineQueryID string                    `json:"inline_query_id"`
+	Results       []InlineQueryResult       `json:"results"`
+	CacheTime     int                       `json:"cache_time,omitempty"`
+	IsPersonal    bool                      `json:"is_personal,omitempty"`
+	NextOffset    string                    `json:"next_offset,omitempty"`
+	Button        *InlineQueryResultsButton `json:"button,omitempty"`
+}
+
+// AnswerInlineQuery send results for the inline query, no more than 50 results are allowed
+func (b *Bot) AnswerInlineQuery(answer ReqAnswerInlineQuery) error {
+	if answer.Results == nil {
+		answer.Results = []InlineQueryResult{}
+	}
+
+	var resp bool
+	return b.Request("answerInlineQuery", answer, &resp)
+}
+
+//
+// Results
+//
+
+// InlineQueryResult is one of the InlineQueryResult* types
+type InlineQueryResult interface {
+	inlineQueryResult()
+}
+
+// InlineResult is common part of the inline query results
+type InlineResult struct {
+	Type                string                `json:"type"`
+	ID                  string                `json:"id"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+	InputMessageContent interface{}           `json:"input_message_content,omitempty"`
+}
+
+func (InlineResult) inlineQueryResult() {}
+
+// InputTextMessageContent is content of the message sent instead of the result
+type InputTextMessageContent struct {
+	MessageText           string `json:"message_text"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
+}
+
+type InlineQueryResultArticle struct {
+	InlineResult
+	Title        string `json:"title"`
+	URL          string `json:"url,omitempty"`
+	HideURL      bool   `json:"hide_url,omitempty"`
+	Description  string `json:"description,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// NewInlineArticle return article which sends the text message
+func NewInlineArticle(id, title, text string) *InlineQueryResultArticle {
+	return &InlineQueryResultArticle{
+		InlineResult: InlineResult{
+			Type:                "article",
+			ID:                  id,
+			InputMessageContent: InputTextMessageContent{MessageText: text},
+		},
+		Title: title,
+	}
+}
+
+type InlineQueryResultPhoto struct {
+	InlineResult
+	PhotoURL     string `json:"photo_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	PhotoWidth   int    `json:"photo_width,omitempty"`
+	PhotoHeight  int    `json:"photo_height,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Caption      string `json:"caption,omitempty"`
+	ParseMode    string `json:"parse_mode,omitempty"`
+}
+
+// NewInlinePhoto return JPEG photo by URL, thumbnail is the photo itself
+func NewInlinePhoto(id, url string) *InlineQueryResultPhoto {
+	return &InlineQueryResultPhoto{
+		InlineResult: InlineResult{Type: "photo", ID: id},
+		PhotoURL:     url,
+		ThumbnailURL: url,
+	}
+}
+
+type InlineQueryResultGif struct {
+	InlineResult
+	GifURL       string `json:"gif_url"`
+	GifWidth     int    `json:"gif_width,omitempty"`
+	GifHeight    int    `json:"gif_height,omitempty"`
+	GifDuration  int    `json:"gif_duration,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Title        string `json:"title,omitempty"`
+	Caption      string `json:"caption,omitempty"`
+	ParseMode    string `json:"parse_mode,omitempty"`
+}
+
+// NewInlineGif return GIF animation by URL, thumbnail is the animation itself
+func NewInlineGif(id, url string) *InlineQueryResultGif {
+	return &InlineQueryResultGif{
+		InlineResult: InlineResult{Type: "gif", ID: id},
+		GifURL:       url,
+		ThumbnailURL: url,
+	}
+}
+
+type InlineQueryResultDocument struct {
+	InlineResult
+	Title        string `json:"title"`
+	DocumentURL  string `json:"document_url"`
+	MimeType     string `json:"mime_type"`
+	Description  string `json:"description,omitempty"`
+	Caption      string `json:"caption,omitempty"`
+	ParseMode    string `json:"parse_mode,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// NewInlineDocument return PDF or ZIP file by URL, mime must be application/pdf or application/zip
+func NewInlineDocument(id, title, url, mime string) *InlineQueryResultDocument {
+	return &InlineQueryResultDocument{
+		InlineResult: InlineResult{Type: "document", ID: id},
+		Title:        title,
+		DocumentURL:  url,
+		MimeType:     mime,
+	}
+}
+
+type InlineQueryResultLocation struct {
+	InlineResult
+	Title string `json:"title"`
+	Location
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+func NewInlineLocation(id, title string, loc Location) *InlineQueryResultLocation {
+	return &InlineQueryResultLocation{
+		InlineResult: InlineResult{Type: "location", ID: id},
+		Title:        title,
+		Location:     loc,
+	}
+}
+
+// InlineQueryResultCached is file stored on the telegram servers,
+// field of file_id depends on the type
+type InlineQueryResultCached struct {
+	InlineResult
+	FileID      string `json:"-"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Caption     string `json:"caption,omitempty"`
+	ParseMode   string `json:"parse_mode,omitempty"`
+}
+
+// Types of cached inline query results
+const (
+	InlineCachedPhoto    = "photo"
+	InlineCachedGif      = "gif"
+	InlineCachedMpeg4Gif = "mpeg4_gif"
+	InlineCachedSticker  = "sticker"
+	InlineCachedDocument = "document"
+	InlineCachedVideo    = "video"
+	InlineCachedVoice    = "voice"
+	InlineCachedAudio    = "audio"
+)
+
+// NewInlineCached return result by file_id of the file of the type,
+// documents, videos and voices require title
+func NewInlineCached(typ, id, fileid string) *InlineQueryResultCached {
+	return &InlineQueryResultCached{
+		InlineResult: InlineResult{Type: typ, ID: id},
+		FileID:       fileid,
+	}
+}
+
+func (r InlineQueryResultCached) MarshalJSON() ([]byte, error) {
+	type result InlineQueryResultCached
+
+	p, err := newParams(result(r))
+	if err != nil {
+		return nil, err
+	}
+
+	// mpeg4_gif is the only type which differs from the field name
+	p.set(strings.TrimSuffix(r.Type, "_gif")+"_file_id", r.FileID)
+
+	return json.Marshal(p)
+}
@@ -0,0 +1,89 @@
+package tebo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHandleInline(t *testing.T) {
+	api := newFakeAPI(t)
+
+	answers := make(chan map[string]json.RawMessage, 2)
+	api.Handle("answerInlineQuery", func(r *http.Request) (interface{}, *ErrorResponse) {
+		var req map[string]json.RawMessage
+		decodeRequest(r, &req)
+		answers <- req
+		return true, nil
+	})
+
+	b := newTestBot(t, api)
+	err := b.HandleInline(`cats (\w+)`, func(ctx *InlineContext) []InlineQueryResult {
+		var results []InlineQueryResult
+		for i := 0; i < 5; i++ {
+			results = append(results, NewInlineArticle(fmt.Sprint(i), ctx.Matches[1], "text"))
+		}
+
+		ctx.IsPersonal = true
+		return ctx.Page(results, 3)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chosen string
+	b.OnChosenInlineResult(func(ctx *Context) *SendMessage {
+		chosen = ctx.ChosenInlineResult.ResultID
+		return nil
+	})
+
+	for _, offset := range []string{"", "3"} {
+		b.route(Update{InlineQuery: &InlineQuery{ID: "q", From: User{ID: 10}, Query: "cats black", Offset: offset}})
+	}
+
+	first, second := <-answers, <-answers
+
+	var results []InlineQueryResultArticle
+	json.Unmarshal(first["results"], &results)
+	if len(results) != 3 || results[0].Title != "black" || results[0].Type != "article" {
+		t.Errorf("unexpected results of the first page %s", first["results"])
+	}
+	if string(first["next_offset"]) != `"3"` || string(first["is_personal"]) != "true" {
+		t.Errorf("unexpected answer of the first page %v", first)
+	}
+
+	json.Unmarshal(second["results"], &results)
+	if len(results) != 2 || results[0].ID != "3" {
+		t.Errorf("unexpected results of the second page %s", second["results"])
+	}
+	if _, ok := second["next_offset"]; ok {
+		t.Errorf("last page should not have next offset")
+	}
+
+	b.route(Update{ChosenInlineResult: &ChosenInlineResult{ResultID: "4", From: User{ID: 10}}})
+	if chosen != "4" {
+		t.Errorf("chosen inline result is not handled, got %q", chosen)
+	}
+}
+
+func TestInlineCachedResult(t *testing.T) {
+	for typ, field := range map[string]string{
+		InlineCachedPhoto:    "photo_file_id",
+		InlineCachedMpeg4Gif: "mpeg4_file_id",
+		InlineCachedDocument: "document_file_id",
+	} {
+		var result InlineQueryResult = NewInlineCached(typ, "1", "abc")
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var m map[string]string
+		json.Unmarshal(data, &m)
+		if m[field] != "abc" || m["type"] != typ || m["id"] != "1" {
+			t.Errorf("unexpected %s result %s", typ, data)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package tebo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is allowed number of requests per period, zero value means no limit
+type Rate struct {
+	Limit int
+	Per   time.Duration
+}
+
+func (r Rate) interval() time.Duration {
+	if r.Limit <= 0 || r.Per <= 0 {
+		return 0
+	}
+
+	return r.Per / time.Duration(r.Limit)
+}
+
+// RateLimit describes limits of outgoing messages,
+// requests exceeding the limits are queued until they can be sent
+type RateLimit struct {
+	// Global limit of messages to all chats
+	Global Rate
+	// Chat is limit of messages to a single private chat
+	Chat Rate
+	// Group is limit of messages to a single group or channel
+	Group Rate
+}
+
+// DefaultRateLimit corresponds to limits of the telegram:
+// 30 messages per second, 1 message per second to a chat
+// and 20 messages per minute to a group
+var DefaultRateLimit = RateLimit{
+	Global: Rate{Limit: 30, Per: time.Second},
+	Chat:   Rate{Limit: 1, Per: time.Second},
+	Group:  Rate{Limit: 20, Per: time.Minute},
+}
+
+// WithRateLimit set limits of outgoing messages, by default used DefaultRateLimit,
+// empty RateLimit disable limiter
+func WithRateLimit(l RateLimit) Option {
+	return func(b *Bot) {
+		b.limiter = newLimiter(l)
+	}
+}
+
+// limitedMethods are prefixes of methods sending or changing messages
+var limitedMethods = []string{"send", "edit", "forward", "copy", "stop"}
+
+func isLimitedMethod(method string) bool {
+	for _, prefix := range limitedMethods {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bucket is a token bucket implemented as GCRA, each reservation
+// shifts the theoretical arrival time on the interval
+type bucket struct {
+	interval time.Duration
+	burst    int
+	tat      time.Time
+}
+
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if b.tat.Before(now) {
+		b.tat = now
+	}
+
+	wait := b.tat.Add(-time.Duration(b.burst-1) * b.interval).Sub(now)
+	b.tat = b.tat.Add(b.interval)
+
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+type limiter struct {
+	RateLimit
+
+	mu        sync.Mutex
+	global    *bucket
+	chats     map[string]*bucket
+	lastSweep time.Time
+}
+
+func newLimiter(l RateLimit) *limiter {
+	lim := &limiter{
+		RateLimit: l,
+		chats:     make(map[string]*bucket),
+	}
+
+	if l.Global.interval() > 0 {
+		lim.global = &bucket{interval: l.Global.interval(), burst: l.Global.Limit}
+	}
+
+	return lim
+}
+
+// reserveChat return delay before the message can be sent to the chat
+func (l *limiter) reserveChat(chatid string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	// ids of groups and channels are negative or it is @username of channel
+	rate := l.Chat
+	if strings.HasPrefix(chatid, "-") || strings.HasPrefix(chatid, "@") {
+		rate = l.Group
+	}
+
+	if rate.interval() == 0 {
+		return 0
+	}
+
+	b, ok := l.chats[chatid]
+	if !ok {
+		b = &bucket{interval: rate.interval(), burst: rate.Limit}
+		l.chats[chatid] = b
+	}
+
+	return b.reserve(now)
+}
+
+func (l *limiter) reserveGlobal(now time.Time) time.Duration {
+	if l.global == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.global.reserve(now)
+}
+
+// sweep remove buckets of chats which are idle long enough to be refilled
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for id, b := range l.chats {
+		if now.Sub(b.tat) > time.Duration(b.burst)*b.interval {
+			delete(l.chats, id)
+		}
+	}
+}
+
+// Wait block until the message can be sent to the chat or the context is done,
+// the chat limit is waited first so one slow chat does not hold the global budget
+func (l *limiter) Wait(ctx context.Context, chatid string) error {
+	if chatid != "" {
+		if err := sleep(ctx, l.reserveChat(chatid, time.Now())); err != nil {
+			return err
+		}
+	}
+
+	return sleep(ctx, l.reserveGlobal(time.Now()))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wait for the limiter if method sends messages
+func (b *Bot) wait(ctx context.Context, method string, chatid string) error {
+	if b.limiter == nil || !isLimitedMethod(method) {
+		return nil
+	}
+
+	return b.limiter.Wait(ctx, chatid)
+}
+
+// payloadChatID extract chat_id from json encoded payload
+func payloadChatID(body []byte) string {
+	var p struct {
+		ChatID json.RawMessage `json:"chat_id"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil || len(p.ChatID) == 0 {
+		return ""
+	}
+
+	return strings.Trim(string(p.ChatID), `"`)
+}
@@ -0,0 +1,65 @@
+package tebo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := &bucket{interval: time.Second, burst: 2}
+	now := time.Now()
+
+	for i, expect := range []time.Duration{0, 0, time.Second, 2 * time.Second} {
+		if d := b.reserve(now); d != expect {
+			t.Errorf("reservation %d: expected delay %s, got %s", i, expect, d)
+		}
+	}
+
+	// after idle period the bucket is refilled
+	now = now.Add(10 * time.Second)
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("expected no delay after idle, got %s", d)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Handle("sendMessage", func(*http.Request) (interface{}, *ErrorResponse) {
+		return Message{MessageID: 1}, nil
+	})
+
+	b := newTestBot(t, api, WithRateLimit(RateLimit{
+		Chat: Rate{Limit: 1, Per: 100 * time.Millisecond},
+	}))
+
+	start := time.Now()
+	for chatid := 1; chatid <= 3; chatid++ {
+		if _, err := b.SendMessage(chatid, NewMessage("text")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("messages to different chats are delayed on %s", d)
+	}
+
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := b.SendMessage(1, NewMessage("text")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 250*time.Millisecond {
+		t.Errorf("messages to the same chat are not limited, sent in %s", d)
+	}
+
+	// queued messages are released on close
+	b.limiter.reserveChat("2", time.Now().Add(time.Hour))
+	go b.Close()
+
+	if _, err := b.SendMessage(2, NewMessage("text")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package tebo
+
+import (
+	"errors"
+	"io"
+)
+
+// InputFile is file to send: new file uploaded from the reader,
+// file already stored on the telegram servers by its file_id,
+// or file downloaded by telegram from the HTTP URL
+type InputFile struct {
+	FileID string
+	URL    string
+
+	Name   string
+	Reader io.Reader
+
+	// Thumbnail of the file, it can be only uploaded
+	Thumbnail *InputFile
+}
+
+// FileByID return file already stored on the telegram servers
+func FileByID(fileid string) InputFile {
+	return InputFile{FileID: fileid}
+}
+
+// FileByURL return file which telegram should download by itself
+func FileByURL(url string) InputFile {
+	return InputFile{URL: url}
+}
+
+// FileFromReader return new file uploaded from the reader
+func FileFromReader(name string, r io.Reader) InputFile {
+	return InputFile{Name: name, Reader: r}
+}
+
+// WithThumbnail return copy of the file with thumbnail uploaded from the reader
+func (f InputFile) WithThumbnail(name string, r io.Reader) InputFile {
+	f.Thumbnail = &InputFile{Name: name, Reader: r}
+	return f
+}
+
+func (f InputFile) isUpload() bool {
+	return f.Reader != nil
+}
+
+// ref return value of the parameter for not uploaded file
+func (f InputFile) ref() string {
+	if f.FileID != "" {
+		return f.FileID
+	}
+
+	return f.URL
+}
+
+// attach set parameter to the file reference or return the file
+// to upload in the field named as parameter, thumbnail is attached by attach:// name
+func (f InputFile) attach(p params, field string) (files []FormFile, err error) {
+	if f.isUpload() {
+		files = append(files, FormFile{field: field, Name: f.Name, Reader: f.Reader})
+	} else if ref := f.ref(); ref != "" {
+		p.set(field, ref)
+	} else {
+		return nil, errors.New("input file is empty")
+	}
+
+	if f.Thumbnail != nil {
+		if !f.Thumbnail.isUpload() {
+			return nil, errors.New("thumbnail can be only uploaded")
+		}
+
+		thumb := field + "_thumbnail"
+		p.set("thumbnail", "attach://"+thumb)
+		files = append(files, FormFile{field: thumb, Name: f.Thumbnail.Name, Reader: f.Thumbnail.Reader})
+	}
+
+	return files, nil
+}
+
+// sendFile send the file in the field of the payload by json or multipart request
+func (b *Bot) sendFile(method, field string, file InputFile, payload interface{}) (msg Message, err error) {
+	p, err := newParams(payload)
+	if err != nil {
+		return msg, err
+	}
+
+	files, err := file.attach(p, field)
+	if err != nil {
+		return msg, err
+	}
+
+	if len(files) == 0 {
+		err = b.Request(method, p, &msg)
+	} else {
+		err = b.FilesRequest(method, files, p, &msg)
+	}
+
+	return
+}
+
+type ReqSendMedia struct {
+	ChatID      int    `json:"chat_id"`
+	Caption     string `json:"caption,omitempty"`
+	SendOptions `json:",omitempty,squash"`
+}
+
+func newReqSendMedia(chatid int, caption string, opt []SendOptions) ReqSendMedia {
+	req := ReqSendMedia{
+		ChatID:  chatid,
+		Caption: caption,
+	}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	return req
+}
+
+func (b *Bot) SendPhoto(chatid int, photo InputFile, caption string, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendPhoto", "photo", photo, newReqSendMedia(chatid, caption, opt))
+}
+
+func (b *Bot) SendDocument(chatid int, document InputFile, caption string, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendDocument", "document", document, newReqSendMedia(chatid, caption, opt))
+}
+
+func (b *Bot) SendVideo(chatid int, video InputFile, caption string, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendVideo", "video", video, newReqSendMedia(chatid, caption, opt))
+}
+
+func (b *Bot) SendAudio(chatid int, audio InputFile, caption string, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendAudio", "audio", audio, newReqSendMedia(chatid, caption, opt))
+}
+
+// SendVoice send audio as voice message, it must be in OGG encoded with OPUS, MP3 or M4A format
+func (b *Bot) SendVoice(chatid int, voice InputFile, caption string, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendVoice", "voice", voice, newReqSendMedia(chatid, caption, opt))
+}
+
+// SendAnimation send GIF or H.264/MPEG-4 AVC video without sound
+func (b *Bot) SendAnimation(chatid int, animation InputFile, caption string, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendAnimation", "animation", animation, newReqSendMedia(chatid, caption, opt))
+}
+
+// SendVideoNote send rounded square video
+func (b *Bot) SendVideoNote(chatid int, note InputFile, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendVideoNote", "video_note", note, newReqSendMedia(chatid, "", opt))
+}
+
+func (b *Bot) SendSticker(chatid int, sticker InputFile, opt ...SendOptions) (Message, error) {
+	return b.sendFile("sendSticker", "sticker", sticker, newReqSendMedia(chatid, "", opt))
+}
+
+//
+// Not files
+//
+
+type ReqSendLocation struct {
+	ChatID      int `json:"chat_id"`
+	Location    `json:",squash"`
+	SendOptions `json:",omitempty,squash"`
+}
+
+func (b *Bot) SendLocation(chatid int, loc Location, opt ...SendOptions) (msg Message, err error) {
+	req := ReqSendLocation{ChatID: chatid, Location: loc}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	err = b.Request("sendLocation", req, &msg)
+	return
+}
+
+type ReqSendVenue struct {
+	ChatID          int     `json:"chat_id"`
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+	Title           string  `json:"title"`
+	Address         string  `json:"address"`
+	FoursquareID    string  `json:"foursquare_id,omitempty"`
+	FoursquareType  string  `json:"foursquare_type,omitempty"`
+	GooglePlaceID   string  `json:"google_place_id,omitempty"`
+	GooglePlaceType string  `json:"google_place_type,omitempty"`
+	SendOptions     `json:",omitempty,squash"`
+}
+
+func (b *Bot) SendVenue(chatid int, venue Venue, opt ...SendOptions) (msg Message, err error) {
+	req := ReqSendVenue{
+		ChatID:          chatid,
+		Latitude:        venue.Location.Latitude,
+		Longitude:       venue.Location.Longitude,
+		Title:           venue.Title,
+		Address:         venue.Address,
+		FoursquareID:    venue.FoursquareID,
+		FoursquareType:  venue.FoursquareType,
+		GooglePlaceID:   venue.GooglePlaceID,
+		GooglePlaceType: venue.GooglePlaceType,
+	}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	err = b.Request("sendVenue", req, &msg)
+	return
+}
+
+type ReqSendContact struct {
+	ChatID      int    `json:"chat_id"`
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name,omitempty"`
+	VCard       string `json:"vcard,omitempty"`
+	SendOptions `json:",omitempty,squash"`
+}
+
+func (b *Bot) SendContact(chatid int, contact Contact, opt ...SendOptions) (msg Message, err error) {
+	req := ReqSendContact{
+		ChatID:      chatid,
+		PhoneNumber: contact.PhoneNumber,
+		FirstName:   contact.FirstName,
+		LastName:    contact.LastName,
+		VCard:       contact.VCard,
+	}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	err = b.Request("sendContact", req, &msg)
+	return
+}
+
+const (
+	DiceCube       = "🎲"
+	DiceDarts      = "🎯"
+	DiceBasketball = "🏀"
+	DiceFootball   = "⚽"
+	DiceBowling    = "🎳"
+	DiceSlots      = "🎰"
+)
+
+type ReqSendDice struct {
+	ChatID      int    `json:"chat_id"`
+	Emoji       string `json:"emoji,omitempty"`
+	SendOptions `json:",omitempty,squash"`
+}
+
+// SendDice send animated emoji with random value, by default it is DiceCube
+func (b *Bot) SendDice(chatid int, emoji string, opt ...SendOptions) (msg Message, err error) {
+	req := ReqSendDice{ChatID: chatid, Emoji: emoji}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	err = b.Request("sendDice", req, &msg)
+	return
+}
+
+const (
+	PollTypeRegular = "regular"
+	PollTypeQuiz    = "quiz"
+)
+
+type InputPollOption struct {
+	Text string `json:"text"`
+}
+
+type SendPoll struct {
+	Question              string            `json:"question"`
+	Options               []InputPollOption `json:"options"`
+	IsAnonymous           *bool             `json:"is_anonymous,omitempty"`
+	Type                  string            `json:"type,omitempty"`
+	AllowsMultipleAnswers bool              `json:"allows_multiple_answers,omitempty"`
+	CorrectOptionID       *int              `json:"correct_option_id,omitempty"`
+	Explanation           string            `json:"explanation,omitempty"`
+	OpenPeriod            int               `json:"open_period,omitempty"`
+	CloseDate             int64             `json:"close_date,omitempty"`
+	IsClosed              bool              `json:"is_closed,omitempty"`
+	SendOptions           `json:",omitempty,squash"`
+}
+
+func NewPoll(question string, options ...string) *SendPoll {
+	poll := &SendPoll{Question: question}
+	for _, text := range options {
+		poll.Options = append(poll.Options, InputPollOption{Text: text})
+	}
+
+	return poll
+}
+
+type ReqSendPoll struct {
+	ChatID   int `json:"chat_id"`
+	SendPoll `json:",squash"`
+}
+
+func (b *Bot) SendPoll(chatid int, poll *SendPoll) (msg Message, err error) {
+	err = b.Request("sendPoll", ReqSendPoll{ChatID: chatid, SendPoll: *poll}, &msg)
+	return
+}
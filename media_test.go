@@ -0,0 +1,78 @@
+package tebo
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSendFile(t *testing.T) {
+	type received struct {
+		params map[string]string
+		files  map[string]string
+	}
+	got := make(chan received, 1)
+
+	api := newFakeAPI(t)
+	api.Handle("sendVideo", func(r *http.Request) (interface{}, *ErrorResponse) {
+		rcv := received{params: make(map[string]string), files: make(map[string]string)}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			r.ParseMultipartForm(1 << 20)
+			for key, val := range r.MultipartForm.Value {
+				rcv.params[key] = val[0]
+			}
+			for key, fh := range r.MultipartForm.File {
+				f, _ := fh[0].Open()
+				data, _ := io.ReadAll(f)
+				rcv.files[key] = string(data)
+			}
+		} else {
+			var p map[string]interface{}
+			decodeRequest(r, &p)
+			for key, val := range p {
+				if s, ok := val.(string); ok {
+					rcv.params[key] = s
+				}
+			}
+		}
+
+		got <- rcv
+		return Message{MessageID: 3, Video: &Video{FileID: "video-id"}}, nil
+	})
+
+	b := newTestBot(t, api)
+
+	file := FileFromReader("clip.mp4", strings.NewReader("video")).
+		WithThumbnail("thumb.jpg", strings.NewReader("thumb"))
+
+	msg, err := b.SendVideo(1, file, "clip", SendOptions{ReplyMarkup: NewInlineKeyboard(1).ToReplyMarkup()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.MessageID != 3 || msg.Video == nil || msg.Video.FileID != "video-id" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	rcv := <-got
+	if rcv.files["video"] != "video" || rcv.files["video_thumbnail"] != "thumb" {
+		t.Errorf("files are not uploaded: %v", rcv.files)
+	}
+	if rcv.params["caption"] != "clip" || rcv.params["thumbnail"] != "attach://video_thumbnail" {
+		t.Errorf("unexpected params %v", rcv.params)
+	}
+	if rcv.params["reply_markup"] != `{"inline_keyboard":null}` {
+		t.Errorf("reply markup is not serialized: %q", rcv.params["reply_markup"])
+	}
+
+	if _, err := b.SendVideo(1, FileByID("video-id"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	rcv = <-got
+	if rcv.params["video"] != "video-id" || len(rcv.files) != 0 {
+		t.Errorf("file id is not passed: %v %v", rcv.params, rcv.files)
+	}
+}
@@ -0,0 +1,288 @@
+package tebo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UpdateMediaGroup is pseudo kind of the update passed to handler
+// registered by OnMediaGroup, it contains all parts of the album
+const UpdateMediaGroup = "media_group"
+
+// WithMediaGroups enable aggregation of incoming albums: messages with the same
+// media_group_id received within the window are passed to handlers at once
+// in Context.MediaGroup
+func WithMediaGroups(window time.Duration) Option {
+	return func(b *Bot) {
+		b.mediaGroupWindow = window
+	}
+}
+
+// OnMediaGroup register handler for albums, if it is not registered
+// then album is routed as its first message
+func (b *Bot) OnMediaGroup(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateMediaGroup, f, mid...)
+}
+
+type mediaGroup struct {
+	chatid  int
+	seq     int
+	updates []Update
+	timer   *time.Timer
+}
+
+// mediaGroups collect parts of albums until no new parts are received within the window,
+// following updates of the chat flush its albums, so the order of updates is kept
+type mediaGroups struct {
+	d      *dispatcher
+	window time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*mediaGroup
+	seq    int
+
+	// flushing is number of albums of the chat removed from groups
+	// but not yet passed to the dispatcher, flushed is signaled when it is done
+	flushing map[int]int
+	flushed  *sync.Cond
+}
+
+func newMediaGroups(d *dispatcher, window time.Duration) *mediaGroups {
+	mg := &mediaGroups{
+		d:        d,
+		window:   window,
+		groups:   make(map[string]*mediaGroup),
+		flushing: make(map[int]int),
+	}
+	mg.flushed = sync.NewCond(&mg.mu)
+
+	return mg
+}
+
+// add the update to its group, return false if update is not a part of an album
+func (mg *mediaGroups) add(u Update) bool {
+	id := updateMessage(u).MediaGroupID
+	if id == "" {
+		return false
+	}
+
+	mg.mu.Lock()
+	defer mg.mu.Unlock()
+
+	g, ok := mg.groups[id]
+	if ok {
+		g.updates = append(g.updates, u)
+		g.timer.Reset(mg.window)
+		return true
+	}
+
+	mg.seq++
+	g = &mediaGroup{chatid: updateKey(u), seq: mg.seq, updates: []Update{u}}
+	g.timer = time.AfterFunc(mg.window, func() { mg.flush(id) })
+	mg.groups[id] = g
+
+	return true
+}
+
+// flush pass the collected album to the dispatcher
+func (mg *mediaGroups) flush(id string) {
+	mg.mu.Lock()
+	g, ok := mg.groups[id]
+	if ok {
+		delete(mg.groups, id)
+		mg.flushing[g.chatid]++
+	}
+	mg.mu.Unlock()
+
+	if ok {
+		mg.dispatch(g)
+	}
+}
+
+// flushChat pass collected albums of the chat to the dispatcher before
+// the following update of the chat, it waits albums flushed concurrently by timers
+func (mg *mediaGroups) flushChat(chatid int) {
+	mg.mu.Lock()
+	var list []*mediaGroup
+	for id, g := range mg.groups {
+		if g.chatid == chatid {
+			g.timer.Stop()
+			delete(mg.groups, id)
+			list = append(list, g)
+		}
+	}
+	mg.flushing[chatid] += len(list)
+	mg.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].seq < list[j].seq })
+
+	for _, g := range list {
+		mg.dispatch(g)
+	}
+
+	mg.mu.Lock()
+	for mg.flushing[chatid] > 0 {
+		mg.flushed.Wait()
+	}
+	mg.mu.Unlock()
+}
+
+// dispatch sort parts of the album and pass it to the dispatcher
+func (mg *mediaGroups) dispatch(g *mediaGroup) {
+	sort.Slice(g.updates, func(i, j int) bool {
+		return updateMessage(g.updates[i]).MessageID < updateMessage(g.updates[j]).MessageID
+	})
+
+	mg.d.dispatchJob(job{Update: g.updates[0], group: g.updates})
+
+	mg.mu.Lock()
+	if mg.flushing[g.chatid]--; mg.flushing[g.chatid] == 0 {
+		delete(mg.flushing, g.chatid)
+	}
+	mg.flushed.Broadcast()
+	mg.mu.Unlock()
+}
+
+// flushAll pass all collected albums without waiting
+func (mg *mediaGroups) flushAll() {
+	mg.mu.Lock()
+	var ids []string
+	for id, g := range mg.groups {
+		if g.timer.Stop() {
+			ids = append(ids, id)
+		}
+	}
+	mg.mu.Unlock()
+
+	for _, id := range ids {
+		mg.flush(id)
+	}
+}
+
+//
+// Send
+//
+
+const (
+	InputMediaPhoto     = "photo"
+	InputMediaVideo     = "video"
+	InputMediaAnimation = "animation"
+	InputMediaAudio     = "audio"
+	InputMediaDocument  = "document"
+)
+
+func NewInputMediaPhoto(file InputFile, caption string) InputMedia {
+	return InputMedia{Type: InputMediaPhoto, File: file, Caption: caption}
+}
+
+func NewInputMediaVideo(file InputFile, caption string) InputMedia {
+	return InputMedia{Type: InputMediaVideo, File: file, Caption: caption}
+}
+
+func NewInputMediaAnimation(file InputFile, caption string) InputMedia {
+	return InputMedia{Type: InputMediaAnimation, File: file, Caption: caption}
+}
+
+func NewInputMediaAudio(file InputFile, caption string) InputMedia {
+	return InputMedia{Type: InputMediaAudio, File: file, Caption: caption}
+}
+
+func NewInputMediaDocument(file InputFile, caption string) InputMedia {
+	return InputMedia{Type: InputMediaDocument, File: file, Caption: caption}
+}
+
+// attach set media to the file reference or to attach:// name,
+// and return files to upload
+func (media *InputMedia) attach(name string) (files []FormFile, err error) {
+	f := media.File
+
+	switch {
+	case f.isUpload():
+		media.Media = "attach://" + name
+		files = append(files, FormFile{field: name, Name: f.Name, Reader: f.Reader})
+	case f.ref() != "":
+		media.Media = f.ref()
+	case media.Media == "":
+		return nil, errors.New("input media file is empty")
+	}
+
+	if f.Thumbnail != nil {
+		if !f.Thumbnail.isUpload() {
+			return nil, errors.New("thumbnail can be only uploaded")
+		}
+
+		thumb := name + "_thumbnail"
+		media.Thumbnail = "attach://" + thumb
+		files = append(files, FormFile{field: thumb, Name: f.Thumbnail.Name, Reader: f.Thumbnail.Reader})
+	}
+
+	return files, nil
+}
+
+type ReqSendMediaGroup struct {
+	ChatID      int          `json:"chat_id"`
+	Media       []InputMedia `json:"media"`
+	SendOptions `json:",omitempty,squash"`
+}
+
+// SendMediaGroup send 2-10 photos, videos, documents or audios as an album,
+// documents and audios can not be mixed with other types
+func (b *Bot) SendMediaGroup(chatid int, media []InputMedia, opt ...SendOptions) (msgs []Message, err error) {
+	req := ReqSendMediaGroup{
+		ChatID: chatid,
+		Media:  make([]InputMedia, len(media)),
+	}
+	if len(opt) > 0 {
+		req.SendOptions = opt[0]
+	}
+
+	var files []FormFile
+	for i, m := range media {
+		f, err := m.attach(fmt.Sprintf("file%d", i))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Media[i] = m
+		files = append(files, f...)
+	}
+
+	if len(files) == 0 {
+		err = b.Request("sendMediaGroup", req, &msgs)
+	} else {
+		err = b.FilesRequest("sendMediaGroup", files, req, &msgs)
+	}
+
+	return
+}
+
+var sendMediaMethods = map[string]string{
+	InputMediaPhoto:     "sendPhoto",
+	InputMediaVideo:     "sendVideo",
+	InputMediaAnimation: "sendAnimation",
+	InputMediaAudio:     "sendAudio",
+	InputMediaDocument:  "sendDocument",
+}
+
+// SendMedia send single media by the method of its type
+func (b *Bot) SendMedia(chatid int, media InputMedia, opt ...SendOptions) (Message, error) {
+	method, ok := sendMediaMethods[media.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown input media type %q", media.Type)
+	}
+
+	req := newReqSendMedia(chatid, media.Caption, opt)
+	if req.ParseMode == "" {
+		req.ParseMode = media.ParseMode
+	}
+
+	file := media.File
+	if !file.isUpload() && file.ref() == "" {
+		file.FileID = media.Media
+	}
+
+	return b.sendFile(method, media.Type, file, req)
+}
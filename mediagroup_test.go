@@ -0,0 +1,110 @@
+package tebo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMediaGroupAggregation(t *testing.T) {
+	b := newOfflineBot(t)
+	b.dispatcher.mediaGroups = newMediaGroups(b.dispatcher, 50*time.Millisecond)
+
+	albums := make(chan []Message, 1)
+	b.OnMediaGroup(func(ctx *Context) *SendMessage {
+		albums <- ctx.MediaGroup
+		return nil
+	})
+
+	for _, id := range []int{3, 1, 2} {
+		u := textUpdate(id, 1, "")
+		u.Message.MediaGroupID = "album"
+		u.Message.Photo = []PhotoSize{{FileID: "photo"}}
+		b.dispatcher.dispatch(u)
+	}
+
+	select {
+	case msgs := <-albums:
+		if len(msgs) != 3 {
+			t.Fatalf("expected 3 parts, got %d", len(msgs))
+		}
+		for i, msg := range msgs {
+			if msg.MessageID != i+1 {
+				t.Errorf("parts are not ordered: %d at %d", msg.MessageID, i)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("album is not received")
+	}
+}
+
+func TestMediaGroupOrder(t *testing.T) {
+	b := newOfflineBot(t)
+	b.dispatcher.mediaGroups = newMediaGroups(b.dispatcher, time.Hour)
+
+	kinds := make(chan string, 2)
+	b.OnMediaGroup(func(ctx *Context) *SendMessage {
+		kinds <- "album"
+		return nil
+	})
+	b.Handle(".*", func(ctx *Context) *SendMessage {
+		kinds <- ctx.Text
+		return nil
+	})
+
+	for id := 1; id <= 2; id++ {
+		u := textUpdate(id, 1, "")
+		u.Message.MediaGroupID = "album"
+		u.Message.Photo = []PhotoSize{{FileID: "photo"}}
+		b.dispatcher.dispatch(u)
+	}
+	b.dispatcher.dispatch(textUpdate(3, 1, "caption"))
+	b.dispatcher.wait()
+
+	close(kinds)
+
+	var got []string
+	for kind := range kinds {
+		got = append(got, kind)
+	}
+
+	if len(got) != 2 || got[0] != "album" || got[1] != "caption" {
+		t.Errorf("updates of the chat are processed out of order: %v", got)
+	}
+}
+
+func TestSendMediaGroup(t *testing.T) {
+	var media []InputMedia
+
+	api := newFakeAPI(t)
+	api.Handle("sendMediaGroup", func(r *http.Request) (interface{}, *ErrorResponse) {
+		r.ParseMultipartForm(1 << 20)
+		json.Unmarshal([]byte(r.FormValue("media")), &media)
+
+		if _, ok := r.MultipartForm.File["file0"]; !ok {
+			return nil, &ErrorResponse{ErrorCode: 400, Description: "file0 is not uploaded"}
+		}
+
+		return []Message{{MessageID: 1}, {MessageID: 2}}, nil
+	})
+
+	b := newTestBot(t, api)
+
+	msgs, err := b.SendMediaGroup(1, []InputMedia{
+		NewInputMediaPhoto(FileFromReader("a.png", strings.NewReader("a")), "first"),
+		NewInputMediaPhoto(FileByID("photo-id"), ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 2 {
+		t.Errorf("expected 2 messages, got %d", len(msgs))
+	}
+
+	if len(media) != 2 || media[0].Media != "attach://file0" || media[0].Caption != "first" || media[1].Media != "photo-id" {
+		t.Errorf("unexpected media %+v", media)
+	}
+}
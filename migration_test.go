@@ -0,0 +1,114 @@
+package tebo
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChatMigration(t *testing.T) {
+	api := newFakeAPI(t)
+
+	var chats []int
+	api.Handle("sendMessage", func(r *http.Request) (interface{}, *ErrorResponse) {
+		var req ReqSendMessage
+		decodeRequest(r, &req)
+		chats = append(chats, req.ChatID)
+
+		if req.ChatID == -2 {
+			return nil, &ErrorResponse{
+				ErrorCode:   400,
+				Description: "Bad Request: group chat was upgraded to a supergroup chat",
+				Parameters:  &ResponseParameters{MigrateToChatID: -200},
+			}
+		}
+		return Message{MessageID: 1, Chat: Chat{ID: req.ChatID}}, nil
+	})
+
+	filename := filepath.Join(t.TempDir(), "history")
+
+	b, err := NewBot(testToken, filename, WithAPIURL(api.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// migration from the service message
+	err = b.updateHistory([]Update{
+		{UpdateID: 1, Message: Message{MessageID: 1, Chat: Chat{ID: -1, Type: "group", Username: "first"}}},
+		{UpdateID: 2, Message: Message{MessageID: 2, Chat: Chat{ID: -2, Type: "group", Username: "second"}}},
+		{UpdateID: 3, Message: Message{MessageID: 3, Chat: Chat{ID: -1, Type: "group"}, MigrateToChatID: -100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok := b.LookupChatID("first"); !ok || id != -100 {
+		t.Errorf("chat is not migrated by service message, id %d", id)
+	}
+
+	// migration from the error response
+	if _, err := b.SendMessage(-2, NewMessage("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chats) != 2 || chats[1] != -200 {
+		t.Errorf("message is not sent again to the new chat: %v", chats)
+	}
+
+	if id, ok := b.LookupChatID("second"); !ok || id != -200 {
+		t.Errorf("chat is not migrated by error, id %d", id)
+	}
+
+	b.Close()
+
+	// migrations are restored from history
+	b, err = NewBot(testToken, filename, WithAPIURL(api.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	for from, to := range map[int]int{-1: -100, -2: -200} {
+		if id, ok := b.Chats.Migrated(from); !ok || id != to {
+			t.Errorf("migration of %d is not restored, got %d", from, id)
+		}
+	}
+
+	if b.UpdateID != 3 {
+		t.Errorf("unexpected last update id %d", b.UpdateID)
+	}
+}
+
+func TestChatMigrationUpload(t *testing.T) {
+	api := newFakeAPI(t)
+
+	var sent int
+	api.Handle("sendDocument", func(r *http.Request) (interface{}, *ErrorResponse) {
+		sent++
+		return nil, &ErrorResponse{
+			ErrorCode:   400,
+			Description: "Bad Request: group chat was upgraded to a supergroup chat",
+			Parameters:  &ResponseParameters{MigrateToChatID: -300},
+		}
+	})
+
+	b := newTestBot(t, api)
+	b.Chats.Get(Chat{ID: -3, Type: "group", Username: "third"})
+
+	// body of the pipe can not be sent again
+	file := FileFromReader("a.txt", io.MultiReader(strings.NewReader("a")))
+	if _, err := b.SendDocument(-3, file, ""); !errors.Is(err, ErrChatMigrated) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if sent != 1 {
+		t.Errorf("not seekable file is sent %d times", sent)
+	}
+
+	if id, ok := b.Chats.Migrated(-3); !ok || id != -300 {
+		t.Errorf("chat is not migrated by error of upload, id %d", id)
+	}
+}
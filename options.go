@@ -0,0 +1,25 @@
+package tebo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Option is optional configuration of the bot passed to NewBot
+type Option func(*Bot)
+
+// WithAPIURL set address of the Bot API server, it allows to use
+// self-hosted server instead of api.telegram.org
+func WithAPIURL(apiurl string) Option {
+	return func(b *Bot) {
+		b.apiurl = strings.TrimSuffix(apiurl, "/")
+	}
+}
+
+// WithHTTPClient set http client used for all requests of the bot,
+// including files downloading, so it is possible to specify proxy, TLS, timeouts
+func WithHTTPClient(c *http.Client) Option {
+	return func(b *Bot) {
+		b.httpClient = c
+	}
+}
@@ -0,0 +1,169 @@
+package tebo
+
+import (
+	"strings"
+	"time"
+)
+
+// HistoryQuery select stored updates, zero fields are not used
+type HistoryQuery struct {
+	// ChatID select updates of the chat, updates of the group
+	// before migration to supergroup are selected by the new id
+	ChatID int
+
+	// UserID select updates initiated by the user
+	UserID int
+
+	// Since and Until select updates by date, updates without date are skipped
+	Since time.Time
+	Until time.Time
+
+	// Text select messages which text or caption contains substring, case is ignored
+	Text string
+
+	// LastPerChat select only the last updates of each chat
+	LastPerChat int
+
+	// messages select only updates with message
+	messages bool
+}
+
+// match return true if the update is selected by the query
+func (q HistoryQuery) match(u Update, chats map[int]bool) bool {
+	if q.messages && updateMessage(u).MessageID == 0 {
+		return false
+	}
+
+	if q.ChatID != 0 && !chats[updateChat(u).ID] {
+		return false
+	}
+
+	if q.UserID != 0 && updateFrom(u).ID != q.UserID {
+		return false
+	}
+
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		date := updateDate(u)
+		if date == 0 {
+			return false
+		}
+		if !q.Since.IsZero() && date < q.Since.Unix() {
+			return false
+		}
+		if !q.Until.IsZero() && date > q.Until.Unix() {
+			return false
+		}
+	}
+
+	if q.Text != "" {
+		msg := updateMessage(u)
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(msg.Text), text) && !strings.Contains(strings.ToLower(msg.Caption), text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// queryHistory return store to query and ids of chats selected by the query
+func (b *Bot) queryHistory(q HistoryQuery) (map[int]bool, HistoryStore, error) {
+	b.historyMu.Lock()
+	store := b.history
+	b.historyMu.Unlock()
+
+	if store == nil {
+		return nil, nil, errHistoryClosed
+	}
+
+	chats := make(map[int]bool)
+	if q.ChatID != 0 {
+		chats[q.ChatID] = true
+		b.Chats.migrated.Range(func(from, to interface{}) bool {
+			if to.(int) == q.ChatID {
+				chats[from.(int)] = true
+			}
+			return true
+		})
+	}
+
+	return chats, store, nil
+}
+
+// QueryUpdates return stored updates selected by the query in order of receiving
+func (b *Bot) QueryUpdates(q HistoryQuery) ([]Update, error) {
+	chats, store, err := b.queryHistory(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []Update
+	perChat := make(map[int][]int)
+
+	err = store.Range(func(u Update) bool {
+		if !q.match(u, chats) {
+			return true
+		}
+
+		if q.LastPerChat > 0 {
+			// group and its supergroup after migration is the same chat
+			key := updateKey(u)
+			if to, ok := b.Chats.Migrated(key); ok {
+				key = to
+			}
+			perChat[key] = append(perChat[key], len(updates))
+		}
+
+		updates = append(updates, u)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if q.LastPerChat <= 0 {
+		return updates, nil
+	}
+
+	keep := make([]bool, len(updates))
+	for _, idx := range perChat {
+		if len(idx) > q.LastPerChat {
+			idx = idx[len(idx)-q.LastPerChat:]
+		}
+		for _, i := range idx {
+			keep[i] = true
+		}
+	}
+
+	var last []Update
+	for i, u := range updates {
+		if keep[i] {
+			last = append(last, u)
+		}
+	}
+
+	return last, nil
+}
+
+// QueryMessages return stored messages selected by the query, including edited messages
+// and channel posts, updates without message are skipped
+func (b *Bot) QueryMessages(q HistoryQuery) ([]Message, error) {
+	q.messages = true
+
+	updates, err := b.QueryUpdates(q)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, len(updates))
+	for i, u := range updates {
+		msgs[i] = updateMessage(u)
+	}
+
+	return msgs, nil
+}
+
+// LastMessages return the last n stored messages of the chat
+func (b *Bot) LastMessages(chatid, n int) ([]Message, error) {
+	return b.QueryMessages(HistoryQuery{ChatID: chatid, LastPerChat: n})
+}
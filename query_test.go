@@ -0,0 +1,73 @@
+package tebo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQueryHistory(t *testing.T) {
+	b := newBot(testToken, WithHistoryStore(NewMemoryHistory()))
+	if err := b.readHistory(""); err != nil {
+		t.Fatal(err)
+	}
+	defer b.closeHistory()
+
+	now := time.Now()
+	msg := func(id, chatid, userid int, text string, age time.Duration) Update {
+		u := textUpdate(id, chatid, text)
+		u.Message.From = User{ID: userid}
+		u.Message.Date = now.Add(-age).Unix()
+		return u
+	}
+
+	b.updateHistory([]Update{
+		msg(1, -1, 7, "hello group", 3*time.Hour),
+		{UpdateID: 2, Message: Message{MessageID: 2, Chat: Chat{ID: -1, Type: "group"}, MigrateToChatID: -100}},
+		msg(3, -100, 8, "Hello supergroup", 2*time.Hour),
+		msg(4, 10, 7, "private", time.Hour),
+		msg(5, 10, 7, "hello private", 0),
+		{UpdateID: 6, CallbackQuery: &CallbackQuery{ID: "1", From: User{ID: 7}, Message: Message{Chat: Chat{ID: 10}}}},
+	})
+
+	ids := func(q HistoryQuery) string {
+		msgs, err := b.QueryMessages(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int
+		for _, msg := range msgs {
+			ids = append(ids, msg.MessageID)
+		}
+		return fmt.Sprint(ids)
+	}
+
+	testCases := []struct {
+		query    HistoryQuery
+		expected string
+	}{
+		{HistoryQuery{ChatID: -100}, "[1 2 3]"},
+		{HistoryQuery{UserID: 7}, "[1 4 5]"},
+		{HistoryQuery{Text: "HELLO"}, "[1 3 5]"},
+		{HistoryQuery{Since: now.Add(-150 * time.Minute), Until: now.Add(-time.Minute)}, "[3 4]"},
+		{HistoryQuery{LastPerChat: 1}, "[3 5]"},
+		{HistoryQuery{ChatID: -100, LastPerChat: 2}, "[2 3]"},
+	}
+
+	for _, tc := range testCases {
+		if got := ids(tc.query); got != tc.expected {
+			t.Errorf("%+v: expected %s, got %s", tc.query, tc.expected, got)
+		}
+	}
+
+	// callback query is not a message, so it is not counted
+	if msgs, _ := b.LastMessages(10, 1); len(msgs) != 1 || msgs[0].MessageID != 5 {
+		t.Errorf("unexpected last messages %+v", msgs)
+	}
+
+	updates, err := b.QueryUpdates(HistoryQuery{ChatID: 10, LastPerChat: 1})
+	if err != nil || len(updates) != 1 || updates[0].UpdateID != 6 {
+		t.Errorf("unexpected last updates %+v, %v", updates, err)
+	}
+}
@@ -0,0 +1,130 @@
+package tebo
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// recordLog is append-only file of records, the last record of the key wins,
+// so the file is rewritten with actual records only on compaction
+type recordLog struct {
+	filename string
+	magic    string
+	codec    *recordCodec
+
+	file    *os.File
+	records int
+}
+
+func newRecordLog(filename, magic string, keys []KeyProvider) *recordLog {
+	var provider KeyProvider
+	if len(keys) > 0 {
+		provider = keys[0]
+	}
+
+	return &recordLog{
+		filename: filename,
+		magic:    magic,
+		codec:    newRecordCodec(provider),
+	}
+}
+
+// read decode records of the file by new values and pass them to f,
+// broken tail is dropped with warning and is cut by the following compaction
+func (l *recordLog) read(newRecord func() interface{}, f func(v interface{})) error {
+	r, err := openHistoryFile(l.filename, l.magic)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	offset := int64(len(l.magic) + 1)
+
+	for {
+		v := newRecord()
+		n, err := l.codec.read(r, offset, v)
+		if err == io.EOF {
+			return nil
+		}
+		if errors.Is(err, ErrHistoryCorrupted) {
+			log.Warningf("%s: %v, following records are dropped", l.filename, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += n
+
+		f(v)
+	}
+}
+
+// compact rewrite the file with records passed to write and reopen it for appending
+func (l *recordLog) compact(records func(write func(v interface{}) error) error) error {
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return err
+		}
+		l.file = nil
+	}
+
+	var count int
+	err := writeHistoryFile(l.filename, l.magic, func(w io.Writer) error {
+		return records(func(v interface{}) error {
+			record, err := l.codec.encode(v)
+			if err != nil {
+				return err
+			}
+
+			count++
+			_, err = w.Write(record)
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	l.records = count
+	l.file, err = os.OpenFile(l.filename, os.O_APPEND|os.O_WRONLY, 0600)
+
+	return err
+}
+
+// append write the record, return true if stale records are too many
+// compared with live number of actual records and file should be compacted
+func (l *recordLog) append(v interface{}, live int) (bool, error) {
+	if l.file == nil {
+		return false, errRecordLogClosed
+	}
+
+	record, err := l.codec.encode(v)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := l.file.Write(record); err != nil {
+		return false, err
+	}
+
+	l.records++
+
+	return l.records > 2*live+1024, nil
+}
+
+func (l *recordLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+var errRecordLogClosed = errors.New("file is closed")
@@ -0,0 +1,103 @@
+package tebo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy describes how failed requests are repeated: on 429 Too Many Requests
+// request is repeated after retry_after seconds, on 5xx and network errors
+// after exponentially growing delay from MinBackoff to MaxBackoff
+type RetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used by WithRetry option if policy fields are not specified
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	MinBackoff: time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// WithRetry enable repeat of failed requests according to the policy
+func WithRetry(p RetryPolicy) Option {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = DefaultRetryPolicy.MaxRetries
+	}
+	if p.MinBackoff == 0 {
+		p.MinBackoff = DefaultRetryPolicy.MinBackoff
+	}
+	if p.MaxBackoff < p.MinBackoff {
+		p.MaxBackoff = p.MinBackoff
+	}
+
+	return func(b *Bot) {
+		b.retryPolicy = &p
+	}
+}
+
+// delay return the time to wait before the next attempt,
+// or false if the error is not transient
+func (p *RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		if e.ErrorCode == http.StatusTooManyRequests && e.RetryAfter() > 0 {
+			return e.RetryAfter(), true
+		}
+		if e.ErrorCode != http.StatusTooManyRequests && e.ErrorCode < 500 {
+			return 0, false
+		}
+	} else {
+		// network errors
+		var ne net.Error
+		if !errors.As(err, &ne) {
+			return 0, false
+		}
+	}
+
+	d := p.MinBackoff << attempt
+	if d > p.MaxBackoff || d <= 0 {
+		d = p.MaxBackoff
+	}
+
+	return d, true
+}
+
+// retry call the function until it succeeds, while the error is transient,
+// retries are enabled and the bot is not closed
+func (b *Bot) retry(ctx context.Context, method string, f func() error) error {
+	return b.retryPolicy.do(ctx, method, f)
+}
+
+// do call the function until it succeeds, while the error is transient
+// and the context is not done, nil policy call it once
+func (p *RetryPolicy) do(ctx context.Context, method string, f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || p == nil || attempt >= p.MaxRetries {
+			return err
+		}
+
+		d, ok := p.delay(attempt, err)
+		if !ok {
+			return err
+		}
+
+		log.Warningf("%s failed: %v, retry in %s", method, err, d)
+
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tebo
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestErrorResponseParameters(t *testing.T) {
+	api := newFakeAPI(t)
+	api.Handle("sendMessage", func(*http.Request) (interface{}, *ErrorResponse) {
+		return nil, &ErrorResponse{
+			ErrorCode:   400,
+			Description: "Bad Request: group chat was upgraded to a supergroup chat",
+			Parameters:  &ResponseParameters{MigrateToChatID: -100123},
+		}
+	})
+
+	b := newTestBot(t, api)
+
+	_, err := b.SendMessage(1, NewMessage("text"))
+
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if e.ErrorCode != 400 || e.Parameters == nil || e.Parameters.MigrateToChatID != -100123 {
+		t.Errorf("parameters are not parsed: %+v", e)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var n int32
+
+	api := newFakeAPI(t)
+	api.Handle("sendMessage", func(*http.Request) (interface{}, *ErrorResponse) {
+		switch atomic.AddInt32(&n, 1) {
+		case 1:
+			return nil, &ErrorResponse{ErrorCode: 502, Description: "Bad Gateway"}
+		case 2:
+			return nil, &ErrorResponse{
+				ErrorCode:   429,
+				Description: "Too Many Requests: retry after 1",
+				Parameters:  &ResponseParameters{RetryAfter: 1},
+			}
+		}
+		return Message{MessageID: 10}, nil
+	})
+
+	b := newTestBot(t, api, WithRetry(RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond}))
+
+	start := time.Now()
+	msgid, err := b.SendMessage(1, NewMessage("text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msgid != 10 || n != 3 {
+		t.Errorf("unexpected message id %d after %d attempts", msgid, n)
+	}
+
+	if time.Since(start) < time.Second {
+		t.Error("retry_after is not respected")
+	}
+}
+
+func TestRetryBadRequest(t *testing.T) {
+	var n int32
+
+	api := newFakeAPI(t)
+	api.Handle("sendMessage", func(*http.Request) (interface{}, *ErrorResponse) {
+		atomic.AddInt32(&n, 1)
+		return nil, &ErrorResponse{ErrorCode: 400, Description: "Bad Request: chat not found"}
+	})
+
+	b := newTestBot(t, api, WithRetry(RetryPolicy{MinBackoff: time.Millisecond}))
+
+	if _, err := b.SendMessage(1, NewMessage("text")); err == nil {
+		t.Error("expected error")
+	}
+
+	if n != 1 {
+		t.Errorf("bad request should not be repeated, made %d attempts", n)
+	}
+}
@@ -0,0 +1,398 @@
+package tebo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// ErrSessionReleased is returned by sessions of the context which is already finished
+	ErrSessionReleased = errors.New("sessions of the context are released")
+
+	// ErrNoUser is returned by user sessions of updates without user,
+	// such as channel posts
+	ErrNoUser = errors.New("update has no user")
+
+	// ErrNoChat is returned by chat sessions of updates without chat,
+	// such as inline queries or poll answers
+	ErrNoChat = errors.New("update has no chat")
+)
+
+// SessionData is stored state of the session, values are encoded by msgpack
+type SessionData struct {
+	Values  map[string][]byte `msgpack:"values"`
+	Expires int64             `msgpack:"expires,omitempty"`
+}
+
+func (d SessionData) expired(now time.Time) bool {
+	return d.Expires > 0 && d.Expires <= now.Unix()
+}
+
+// SessionStore keep sessions between updates and restarts
+type SessionStore interface {
+	// Load return data of the session, false if session is not found or expired
+	Load(key string) (SessionData, bool, error)
+
+	Save(key string, data SessionData) error
+
+	Delete(key string) error
+
+	Close() error
+}
+
+// WithSessions set storage of sessions, sessions not changed for ttl are expired,
+// zero ttl keeps sessions forever, by default sessions are kept in memory
+func WithSessions(store SessionStore, ttl time.Duration) Option {
+	return func(b *Bot) {
+		b.sessions = newSessions(store, ttl)
+	}
+}
+
+// Session is persistent key/value bag of the chat, the user or the user in the chat,
+// it is loaded by the first call of Context.Session and saved after the handler,
+// the same session is shared by concurrent handlers
+type Session struct {
+	key string
+
+	mu     sync.Mutex
+	values map[string][]byte
+	dirty  bool
+
+	// refs is number of contexts which use the session, guarded by sessions.mu
+	refs int
+}
+
+// Get decode value of the key to v, return false if there is no value
+func (s *Session) Get(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(key, v)
+}
+
+func (s *Session) get(key string, v interface{}) (bool, error) {
+	data, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+
+	return true, msgpack.Unmarshal(data, v)
+}
+
+// Set value of the key
+func (s *Session) Set(key string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.set(key, v)
+}
+
+func (s *Session) set(key string, v interface{}) error {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.values[key] = data
+	s.dirty = true
+
+	return nil
+}
+
+// Modify decode value of the key to v, call f and store changed v,
+// session is locked, so concurrent handlers do not lose changes
+func (s *Session) Modify(key string, v interface{}, f func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.get(key, v); err != nil {
+		return err
+	}
+
+	if err := f(); err != nil {
+		return err
+	}
+
+	return s.set(key, v)
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// Clear remove all values of the session
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values = make(map[string][]byte)
+	s.dirty = true
+}
+
+// Keys return sorted keys of the session
+func (s *Session) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// sessions keep sessions used by handlers
+type sessions struct {
+	store SessionStore
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	active map[string]*Session
+}
+
+func newSessions(store SessionStore, ttl time.Duration) *sessions {
+	return &sessions{
+		store:  store,
+		ttl:    ttl,
+		active: make(map[string]*Session),
+	}
+}
+
+// acquire return session used by other handlers or load it from the store
+func (m *sessions) acquire(key string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.active[key]; ok {
+		s.refs++
+		return s, nil
+	}
+
+	data, ok, err := m.store.Load(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session %s: %v", key, err)
+	}
+
+	s := &Session{key: key, values: data.Values, refs: 1}
+	if !ok || s.values == nil {
+		s.values = make(map[string][]byte)
+	}
+
+	m.active[key] = s
+
+	return s, nil
+}
+
+// save store the session if it is changed
+func (m *sessions) save(s *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	var err error
+	if len(s.values) == 0 {
+		err = m.store.Delete(s.key)
+	} else {
+		data := SessionData{Values: make(map[string][]byte, len(s.values))}
+		for key, val := range s.values {
+			data.Values[key] = val
+		}
+		if m.ttl > 0 {
+			data.Expires = time.Now().Add(m.ttl).Unix()
+		}
+
+		err = m.store.Save(s.key, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save session %s: %v", s.key, err)
+	}
+
+	s.dirty = false
+
+	return nil
+}
+
+// release save the session and forget it if it is not used by other handlers
+func (m *sessions) release(s *Session) error {
+	err := m.save(s)
+
+	m.mu.Lock()
+	if s.refs--; s.refs == 0 {
+		delete(m.active, s.key)
+	}
+	m.mu.Unlock()
+
+	return err
+}
+
+//
+// Context
+//
+
+// Session return session of the user in the current chat
+func (ctx *Context) Session() (*Session, error) {
+	if ctx.Chat.ID == 0 {
+		return nil, ErrNoChat
+	}
+	if ctx.From.ID == 0 {
+		return nil, ErrNoUser
+	}
+
+	return ctx.session(fmt.Sprintf("chat:%d:user:%d", ctx.Chat.ID, ctx.From.ID))
+}
+
+// ChatSession return session shared by all users of the current chat
+func (ctx *Context) ChatSession() (*Session, error) {
+	if ctx.Chat.ID == 0 {
+		return nil, ErrNoChat
+	}
+
+	return ctx.session(fmt.Sprintf("chat:%d", ctx.Chat.ID))
+}
+
+// UserSession return session of the current user shared by all chats
+func (ctx *Context) UserSession() (*Session, error) {
+	if ctx.From.ID == 0 {
+		return nil, ErrNoUser
+	}
+
+	return ctx.session(fmt.Sprintf("user:%d", ctx.From.ID))
+}
+
+func (ctx *Context) session(key string) (*Session, error) {
+	ctx.sessionsMu.Lock()
+	defer ctx.sessionsMu.Unlock()
+
+	if ctx.sessionsReleased {
+		return nil, ErrSessionReleased
+	}
+
+	for _, s := range ctx.sessions {
+		if s.key == key {
+			return s, nil
+		}
+	}
+
+	s, err := ctx.Bot.getSessions().acquire(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.sessions = append(ctx.sessions, s)
+
+	return s, nil
+}
+
+// SaveSessions store changes of sessions of the context, it is called after handlers
+func (ctx *Context) SaveSessions() (err error) {
+	ctx.sessionsMu.Lock()
+	defer ctx.sessionsMu.Unlock()
+
+	for _, s := range ctx.sessions {
+		if e := ctx.Bot.sessions.save(s); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// releaseSessions save and release sessions after routing of the update
+func (ctx *Context) releaseSessions() {
+	ctx.sessionsMu.Lock()
+	defer ctx.sessionsMu.Unlock()
+
+	for _, s := range ctx.sessions {
+		if err := ctx.Bot.sessions.release(s); err != nil {
+			log.Error(err)
+		}
+	}
+
+	ctx.sessions = nil
+	ctx.sessionsReleased = true
+}
+
+// getSessions return sessions of the bot, by default they are kept in memory
+func (b *Bot) getSessions() *sessions {
+	b.sessionsOnce.Do(func() {
+		if b.sessions == nil {
+			b.sessions = newSessions(NewMemorySessions(), 0)
+		}
+	})
+
+	return b.sessions
+}
+
+// closeSessions close the session store
+func (b *Bot) closeSessions() error {
+	if b.sessions == nil {
+		return nil
+	}
+
+	return b.sessions.store.Close()
+}
+
+//
+// Memory
+//
+
+// MemorySessions keep sessions in memory, they are lost on restart
+type MemorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]SessionData
+}
+
+func NewMemorySessions() *MemorySessions {
+	return &MemorySessions{sessions: make(map[string]SessionData)}
+}
+
+func (m *MemorySessions) Load(key string) (SessionData, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.sessions[key]
+	if ok && data.expired(time.Now()) {
+		delete(m.sessions, key)
+		return SessionData{}, false, nil
+	}
+
+	return data, ok, nil
+}
+
+func (m *MemorySessions) Save(key string, data SessionData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[key] = data
+
+	return nil
+}
+
+func (m *MemorySessions) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, key)
+
+	return nil
+}
+
+func (m *MemorySessions) Close() error {
+	return nil
+}
@@ -0,0 +1,180 @@
+package tebo
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sessions")
+
+	store, err := NewFileSessions(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newOfflineBot(t)
+	b.sessions = newSessions(store, time.Hour)
+
+	b.Handle("/count", func(ctx *Context) *SendMessage {
+		s, err := ctx.Session()
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+
+		var n int
+		if err := s.Modify("count", &n, func() error { n++; return nil }); err != nil {
+			t.Error(err)
+		}
+
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		u := textUpdate(i, 1, "/count")
+		u.Message.From = User{ID: 5}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.routeContext(b.newContext(u))
+		}()
+	}
+	wg.Wait()
+
+	if len(b.sessions.active) != 0 {
+		t.Errorf("sessions are not released: %d", len(b.sessions.active))
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// sessions are restored from the file
+	store, err = NewFileSessions(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	sessions := newSessions(store, time.Hour)
+	s, err := sessions.acquire("chat:1:user:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if ok, err := s.Get("count", &n); !ok || err != nil || n != 10 {
+		t.Errorf("unexpected count %d, %v, %v", n, ok, err)
+	}
+
+	s.Clear()
+	if err := sessions.release(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := store.Load("chat:1:user:5"); ok {
+		t.Error("empty session is not deleted")
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	store := NewMemorySessions()
+	store.Save("user:1", SessionData{
+		Values:  map[string][]byte{"a": {1}},
+		Expires: time.Now().Add(-time.Second).Unix(),
+	})
+
+	s, err := newSessions(store, time.Minute).acquire("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("expired session is loaded: %v", keys)
+	}
+}
+
+func TestSessionExpectAnswer(t *testing.T) {
+	store := NewMemorySessions()
+
+	b := newOfflineBot(t)
+	b.sessions = newSessions(store, 0)
+
+	b.Handle("/name", func(ctx *Context) *SendMessage {
+		actx, ok := ctx.ExpectAnswer()
+		if !ok {
+			return nil
+		}
+
+		s, err := actx.Session()
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+
+		if err := s.Set("name", actx.Text); err != nil {
+			t.Error(err)
+		}
+		return nil
+	})
+
+	ask := textUpdate(1, 1, "/name")
+	ask.Message.From = User{ID: 5}
+	answer := textUpdate(2, 1, "gopher")
+	answer.Message.From = User{ID: 5}
+
+	b.dispatcher.dispatch(ask)
+	b.dispatcher.dispatch(answer)
+	b.dispatcher.wait()
+
+	if len(b.sessions.active) != 0 {
+		t.Errorf("sessions are not released: %d", len(b.sessions.active))
+	}
+
+	data, ok, _ := store.Load("chat:1:user:5")
+	if !ok {
+		t.Fatal("session of the answer is not saved")
+	}
+
+	var name string
+	s := &Session{values: data.Values}
+	if ok, err := s.Get("name", &name); !ok || err != nil || name != "gopher" {
+		t.Errorf("unexpected name %q, %v, %v", name, ok, err)
+	}
+}
+
+func TestSessionNoUser(t *testing.T) {
+	b := newOfflineBot(t)
+
+	ctx := b.newContext(Update{ChannelPost: &Message{MessageID: 1, Chat: Chat{ID: -100, Type: "channel"}}})
+	defer ctx.finish()
+
+	if _, err := ctx.Session(); err != ErrNoUser {
+		t.Errorf("unexpected error of the session without user: %v", err)
+	}
+	if _, err := ctx.UserSession(); err != ErrNoUser {
+		t.Errorf("unexpected error of the user session without user: %v", err)
+	}
+	if _, err := ctx.ChatSession(); err != nil {
+		t.Errorf("chat session of the channel: %v", err)
+	}
+
+	// inline query has user, but no chat
+	ctx = b.newContext(Update{InlineQuery: &InlineQuery{ID: "1", From: User{ID: 5}}})
+	defer ctx.finish()
+
+	if _, err := ctx.ChatSession(); err != ErrNoChat {
+		t.Errorf("unexpected error of the chat session without chat: %v", err)
+	}
+	if _, err := ctx.Session(); err != ErrNoChat {
+		t.Errorf("unexpected error of the session without chat: %v", err)
+	}
+	if _, err := ctx.UserSession(); err != nil {
+		t.Errorf("user session of the inline query: %v", err)
+	}
+}
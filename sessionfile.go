@@ -0,0 +1,129 @@
+package tebo
+
+import (
+	"sync"
+	"time"
+)
+
+const sessionMagic = "TEBOSES"
+
+// sessionRecord is change of the session, the last record of the key wins
+type sessionRecord struct {
+	Key     string      `msgpack:"key"`
+	Data    SessionData `msgpack:"data,omitempty"`
+	Deleted bool        `msgpack:"deleted,omitempty"`
+}
+
+// FileSessions keep sessions in memory and append changes to the file,
+// the file is compacted on open and when it contains too many stale records
+type FileSessions struct {
+	mu       sync.Mutex
+	log      *recordLog
+	sessions map[string]SessionData
+}
+
+// NewFileSessions open file of sessions, records are encrypted if keys are passed
+func NewFileSessions(filename string, keys ...KeyProvider) (*FileSessions, error) {
+	s := &FileSessions{
+		log:      newRecordLog(filename, sessionMagic, keys),
+		sessions: make(map[string]SessionData),
+	}
+
+	now := time.Now()
+	err := s.log.read(func() interface{} { return new(sessionRecord) }, func(v interface{}) {
+		rec := v.(*sessionRecord)
+		if rec.Deleted || rec.Data.expired(now) {
+			delete(s.sessions, rec.Key)
+		} else {
+			s.sessions[rec.Key] = rec.Data
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// compact rewrite the file with actual sessions only
+func (s *FileSessions) compact() error {
+	now := time.Now()
+
+	return s.log.compact(func(write func(v interface{}) error) error {
+		for key, data := range s.sessions {
+			if data.expired(now) {
+				delete(s.sessions, key)
+				continue
+			}
+
+			if err := write(sessionRecord{Key: key, Data: data}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *FileSessions) append(rec sessionRecord) error {
+	compact, err := s.log.append(rec, len(s.sessions))
+	if err != nil || !compact {
+		return err
+	}
+
+	return s.compact()
+}
+
+func (s *FileSessions) Load(key string) (SessionData, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.sessions[key]
+	if ok && data.expired(time.Now()) {
+		return SessionData{}, false, nil
+	}
+
+	return data, ok, nil
+}
+
+func (s *FileSessions) Save(key string, data SessionData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[key] = data
+
+	return s.append(sessionRecord{Key: key, Data: data})
+}
+
+func (s *FileSessions) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[key]; !ok {
+		return nil
+	}
+
+	delete(s.sessions, key)
+
+	return s.append(sessionRecord{Key: key, Deleted: true})
+}
+
+// Close compact and close the file
+func (s *FileSessions) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log.file == nil {
+		return nil
+	}
+
+	err := s.compact()
+	if e := s.log.close(); err == nil {
+		err = e
+	}
+
+	return err
+}
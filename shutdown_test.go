@@ -0,0 +1,90 @@
+package tebo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	b := newOfflineBot(t)
+	b.dispatcher = newDispatcher(b, 1)
+
+	release := make(chan struct{})
+	expectOk := make(chan bool, 1)
+
+	b.Handle("/ask", func(ctx *Context) *SendMessage {
+		_, ok := ctx.ExpectAnswer()
+		expectOk <- ok
+		return nil
+	})
+	b.Handle("/slow", func(ctx *Context) *SendMessage {
+		<-release
+		return nil
+	})
+
+	b.dispatcher.dispatch(textUpdate(1, 1, "/ask"))
+	b.dispatcher.dispatch(textUpdate(2, 2, "/slow"))
+	b.dispatcher.dispatch(textUpdate(3, 2, "/slow"))
+
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := b.Shutdown(ctx)
+
+	var e *ShutdownError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected ShutdownError, got %v", err)
+	}
+	if e.Running != 1 || len(e.Pending) != 1 || e.Pending[0].UpdateID != 3 {
+		t.Errorf("unexpected abandoned handlers: %d, updates: %+v", e.Running, e.Pending)
+	}
+
+	select {
+	case ok := <-expectOk:
+		if ok {
+			t.Error("expect answer should be canceled")
+		}
+	case <-time.After(time.Second):
+		t.Error("handler waiting for answer is not released")
+	}
+
+	if err := b.updateHistory([]Update{textUpdate(4, 1, "text")}); !errors.Is(err, errHistoryClosed) {
+		t.Errorf("history is not closed: %v", err)
+	}
+
+	close(release)
+	b.dispatcher.wait()
+}
+
+func TestShutdownExpectAnswer(t *testing.T) {
+	b := newOfflineBot(t)
+
+	expectOk := make(chan bool, 2)
+	b.UpdatesHandle(func(ctx *Context) bool {
+		_, ok := ctx.ExpectAnswer()
+		expectOk <- ok
+		return false
+	})
+
+	// handler of update without chat can not be answered
+	go b.route(Update{UpdateID: 1, InlineQuery: &InlineQuery{ID: "1", From: User{ID: 1}}})
+
+	// handler started waiting after cancellation of waiting handlers
+	b.closed.Store(true)
+	go b.route(textUpdate(2, 1, "text"))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case ok := <-expectOk:
+			if ok {
+				t.Error("expect answer should be canceled")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("handler waiting for answer is not released")
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package tebo
+
+import "sync"
+
+// HistoryStore keep received updates, it is used to restore known chats
+// and the offset of updates after restart
+type HistoryStore interface {
+	// Append store updates in order of receiving
+	Append(updates []Update) error
+
+	// LastUpdateID return id of the last stored update received by the bot,
+	// imported updates are not counted
+	LastUpdateID() (int, error)
+
+	// Range pass stored updates in order of storing until f returns false
+	Range(f func(Update) bool) error
+
+	// Chats pass each chat of stored updates once until f returns false,
+	// chat has the last known state
+	Chats(f func(Chat) bool) error
+
+	// Migrations pass ids of groups upgraded to supergroups and the new ids
+	Migrations(f func(from, to int) bool) error
+
+	Close() error
+}
+
+// WithHistoryStore set storage of updates history, if it is set
+// then history file passed to NewBot is not used
+func WithHistoryStore(store HistoryStore) Option {
+	return func(b *Bot) {
+		b.history = store
+	}
+}
+
+// historyState is the registry of chats collected from updates,
+// it is kept by stores to not replay all updates on startup
+type historyState struct {
+	LastUpdateID int         `msgpack:"last_update_id"`
+	Chats        []Chat      `msgpack:"chats"`
+	Migrations   map[int]int `msgpack:"migrations"`
+
+	index map[int]int
+}
+
+func newHistoryState() *historyState {
+	return &historyState{
+		Migrations: make(map[int]int),
+		index:      make(map[int]int),
+	}
+}
+
+// add update to the registry, imported updates do not move the offset of polling
+func (s *historyState) add(u Update) {
+	if u.UpdateID > s.LastUpdateID && !u.Imported {
+		s.LastUpdateID = u.UpdateID
+	}
+
+	chat := updateChat(u)
+	if chat.ID == 0 {
+		return
+	}
+
+	if s.index == nil {
+		s.reindex()
+	}
+
+	if i, ok := s.index[chat.ID]; ok {
+		s.Chats[i] = chat
+	} else {
+		s.index[chat.ID] = len(s.Chats)
+		s.Chats = append(s.Chats, chat)
+	}
+
+	if s.Migrations == nil {
+		s.Migrations = make(map[int]int)
+	}
+
+	if msg := updateMessage(u); msg.MigrateToChatID != 0 {
+		s.Migrations[chat.ID] = msg.MigrateToChatID
+	} else if msg.MigrateFromChatID != 0 {
+		s.Migrations[msg.MigrateFromChatID] = chat.ID
+	}
+}
+
+// reindex restore index of chats of decoded state
+func (s *historyState) reindex() {
+	s.index = make(map[int]int, len(s.Chats))
+	for i, chat := range s.Chats {
+		s.index[chat.ID] = i
+	}
+}
+
+func (s *historyState) rangeChats(f func(Chat) bool) {
+	for _, chat := range s.Chats {
+		if !f(chat) {
+			return
+		}
+	}
+}
+
+func (s *historyState) rangeMigrations(f func(from, to int) bool) {
+	for from, to := range s.Migrations {
+		if !f(from, to) {
+			return
+		}
+	}
+}
+
+// loadState collect registry by all stored updates
+func loadState(store HistoryStore) (*historyState, error) {
+	s := newHistoryState()
+
+	err := store.Range(func(u Update) bool {
+		s.add(u)
+		return true
+	})
+
+	return s, err
+}
+
+//
+// Memory
+//
+
+// MemoryHistory keep updates in memory, it is useful for tests
+type MemoryHistory struct {
+	mu      sync.Mutex
+	updates []Update
+	closed  bool
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return new(MemoryHistory)
+}
+
+func (h *MemoryHistory) Append(updates []Update) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return errHistoryClosed
+	}
+
+	h.updates = append(h.updates, updates...)
+
+	return nil
+}
+
+func (h *MemoryHistory) LastUpdateID() (int, error) {
+	s, err := loadState(h)
+	return s.LastUpdateID, err
+}
+
+func (h *MemoryHistory) Range(f func(Update) bool) error {
+	h.mu.Lock()
+	updates := h.updates[:len(h.updates):len(h.updates)]
+	h.mu.Unlock()
+
+	for _, u := range updates {
+		if !f(u) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (h *MemoryHistory) Chats(f func(Chat) bool) error {
+	s, err := loadState(h)
+	if err != nil {
+		return err
+	}
+
+	s.rangeChats(f)
+
+	return nil
+}
+
+func (h *MemoryHistory) Migrations(f func(from, to int) bool) error {
+	s, err := loadState(h)
+	if err != nil {
+		return err
+	}
+
+	s.rangeMigrations(f)
+
+	return nil
+}
+
+func (h *MemoryHistory) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+
+	return nil
+}
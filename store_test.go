@@ -0,0 +1,92 @@
+package tebo
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func testHistoryStore(t *testing.T, store HistoryStore) {
+	err := store.Append([]Update{
+		textUpdate(1, 10, "a"),
+		textUpdate(2, 20, "b"),
+		textUpdate(3, 10, "c"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	store.Range(func(u Update) bool {
+		ids = append(ids, u.UpdateID)
+		return true
+	})
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("unexpected stored updates %v", ids)
+	}
+
+	if id, err := store.LastUpdateID(); err != nil || id != 3 {
+		t.Errorf("unexpected last update id %d, %v", id, err)
+	}
+
+	var chats []int
+	store.Chats(func(chat Chat) bool {
+		chats = append(chats, chat.ID)
+		return true
+	})
+	if len(chats) != 2 || chats[0] != 10 || chats[1] != 20 {
+		t.Errorf("unexpected chats %v", chats)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Append([]Update{textUpdate(4, 10, "d")}); !errors.Is(err, errHistoryClosed) {
+		t.Errorf("append to closed store: %v", err)
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+
+	store, err := NewFileHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHistoryStore(t, store)
+
+	// updates are kept after reopening
+	store, err = NewFileHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if id, err := store.LastUpdateID(); err != nil || id != 3 {
+		t.Errorf("unexpected last update id after reopening %d, %v", id, err)
+	}
+}
+
+func TestMemoryHistory(t *testing.T) {
+	testHistoryStore(t, NewMemoryHistory())
+}
+
+func TestWithHistoryStore(t *testing.T) {
+	store := NewMemoryHistory()
+	store.Append([]Update{textUpdate(5, 10, "a")})
+
+	b := newBot(testToken, WithHistoryStore(store))
+	if err := b.readHistory(""); err != nil {
+		t.Fatal(err)
+	}
+	defer b.closeHistory()
+
+	if b.UpdateID != 5 {
+		t.Errorf("unexpected last update id %d", b.UpdateID)
+	}
+
+	if _, ok := b.Chats.chats.Load(10); !ok {
+		t.Errorf("chat is not restored from the store")
+	}
+}
@@ -20,23 +20,84 @@ type Update struct {
 	UpdateID int     `json:"update_id"`
 	Message  Message `json:"message"`
 
-	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+	EditedMessage        *Message                     `json:"edited_message,omitempty"`
+	ChannelPost          *Message                     `json:"channel_post,omitempty"`
+	EditedChannelPost    *Message                     `json:"edited_channel_post,omitempty"`
+	MessageReaction      *MessageReactionUpdated      `json:"message_reaction,omitempty"`
+	MessageReactionCount *MessageReactionCountUpdated `json:"message_reaction_count,omitempty"`
+	InlineQuery          *InlineQuery                 `json:"inline_query,omitempty"`
+	ChosenInlineResult   *ChosenInlineResult          `json:"chosen_inline_result,omitempty"`
+	CallbackQuery        *CallbackQuery               `json:"callback_query,omitempty"`
+	ShippingQuery        *ShippingQuery               `json:"shipping_query,omitempty"`
+	PreCheckoutQuery     *PreCheckoutQuery            `json:"pre_checkout_query,omitempty"`
+	Poll                 *Poll                        `json:"poll,omitempty"`
+	PollAnswer           *PollAnswer                  `json:"poll_answer,omitempty"`
+	MyChatMember         *ChatMemberUpdated           `json:"my_chat_member,omitempty"`
+	ChatMember           *ChatMemberUpdated           `json:"chat_member,omitempty"`
+	ChatJoinRequest      *ChatJoinRequest             `json:"chat_join_request,omitempty"`
+
+	// Imported is set for updates added by ImportHistory, they are not received
+	// by the bot, so they do not move the offset of polling
+	Imported bool `json:"-" msgpack:"imported,omitempty"`
 }
 
 type Message struct {
-	MessageID int             `json:"message_id"`
-	From      User            `json:"from,omitempty"`
-	Chat      Chat            `json:"chat"`
-	Date      int64           `json:"date"`
-	Text      string          `json:"text"`
-	Entities  []MessageEntity `json:"entities,omitempty"`
-
-	// ...
-
-	Document *Document   `json:"document,omitempty"`
-	Photo    []PhotoSize `json:"photo,omitempty"`
-
-	// ...
+	MessageID       int   `json:"message_id"`
+	MessageThreadID int   `json:"message_thread_id,omitempty"`
+	From            User  `json:"from,omitempty"`
+	SenderChat      *Chat `json:"sender_chat,omitempty"`
+	Chat            Chat  `json:"chat"`
+	Date            int64 `json:"date"`
+
+	ForwardOrigin        *MessageOrigin `json:"forward_origin,omitempty"`
+	ForwardFrom          *User          `json:"forward_from,omitempty"`
+	ForwardFromChat      *Chat          `json:"forward_from_chat,omitempty"`
+	ForwardFromMessageID int            `json:"forward_from_message_id,omitempty"`
+	ForwardSignature     string         `json:"forward_signature,omitempty"`
+	ForwardSenderName    string         `json:"forward_sender_name,omitempty"`
+	ForwardDate          int64          `json:"forward_date,omitempty"`
+	IsTopicMessage       bool           `json:"is_topic_message,omitempty"`
+	IsAutomaticForward   bool           `json:"is_automatic_forward,omitempty"`
+
+	ReplyToMessage      *Message `json:"reply_to_message,omitempty"`
+	ViaBot              *User    `json:"via_bot,omitempty"`
+	EditDate            int64    `json:"edit_date,omitempty"`
+	HasProtectedContent bool     `json:"has_protected_content,omitempty"`
+	MediaGroupID        string   `json:"media_group_id,omitempty"`
+	AuthorSignature     string   `json:"author_signature,omitempty"`
+
+	Text     string          `json:"text"`
+	Entities []MessageEntity `json:"entities,omitempty"`
+
+	Animation       *Animation      `json:"animation,omitempty"`
+	Audio           *Audio          `json:"audio,omitempty"`
+	Document        *Document       `json:"document,omitempty"`
+	Photo           []PhotoSize     `json:"photo,omitempty"`
+	Sticker         *Sticker        `json:"sticker,omitempty"`
+	Video           *Video          `json:"video,omitempty"`
+	VideoNote       *VideoNote      `json:"video_note,omitempty"`
+	Voice           *Voice          `json:"voice,omitempty"`
+	Caption         string          `json:"caption,omitempty"`
+	CaptionEntities []MessageEntity `json:"caption_entities,omitempty"`
+	HasMediaSpoiler bool            `json:"has_media_spoiler,omitempty"`
+	Contact         *Contact        `json:"contact,omitempty"`
+	Dice            *Dice           `json:"dice,omitempty"`
+	Poll            *Poll           `json:"poll,omitempty"`
+	Venue           *Venue          `json:"venue,omitempty"`
+	Location        *Location       `json:"location,omitempty"`
+
+	// service messages
+	NewChatMembers        []User      `json:"new_chat_members,omitempty"`
+	LeftChatMember        *User       `json:"left_chat_member,omitempty"`
+	NewChatTitle          string      `json:"new_chat_title,omitempty"`
+	NewChatPhoto          []PhotoSize `json:"new_chat_photo,omitempty"`
+	DeleteChatPhoto       bool        `json:"delete_chat_photo,omitempty"`
+	GroupChatCreated      bool        `json:"group_chat_created,omitempty"`
+	SupergroupChatCreated bool        `json:"supergroup_chat_created,omitempty"`
+	ChannelChatCreated    bool        `json:"channel_chat_created,omitempty"`
+	MigrateToChatID       int         `json:"migrate_to_chat_id,omitempty"`
+	MigrateFromChatID     int         `json:"migrate_from_chat_id,omitempty"`
+	PinnedMessage         *Message    `json:"pinned_message,omitempty"`
 
 	ConnectedWebsite string                `json:"connected_website,omitempty"`
 	PassportData     *PassportData         `json:"passport_data,omitempty"`
@@ -81,6 +142,201 @@ type CallbackQuery struct {
 	GameShortName   string  `json:"game_short_name,omitempty"`
 }
 
+type InlineQuery struct {
+	ID       string    `json:"id"`
+	From     User      `json:"from"`
+	Query    string    `json:"query"`
+	Offset   string    `json:"offset"`
+	ChatType string    `json:"chat_type,omitempty"`
+	Location *Location `json:"location,omitempty"`
+}
+
+type ChosenInlineResult struct {
+	ResultID        string    `json:"result_id"`
+	From            User      `json:"from"`
+	Location        *Location `json:"location,omitempty"`
+	InlineMessageID string    `json:"inline_message_id,omitempty"`
+	Query           string    `json:"query"`
+}
+
+type Location struct {
+	Longitude            float64 `json:"longitude"`
+	Latitude             float64 `json:"latitude"`
+	HorizontalAccuracy   float64 `json:"horizontal_accuracy,omitempty"`
+	LivePeriod           int     `json:"live_period,omitempty"`
+	Heading              int     `json:"heading,omitempty"`
+	ProximityAlertRadius int     `json:"proximity_alert_radius,omitempty"`
+}
+
+//
+// Payments
+//
+
+type ShippingQuery struct {
+	ID              string          `json:"id"`
+	From            User            `json:"from"`
+	InvoicePayload  string          `json:"invoice_payload"`
+	ShippingAddress ShippingAddress `json:"shipping_address"`
+}
+
+type ShippingAddress struct {
+	CountryCode string `json:"country_code"`
+	State       string `json:"state"`
+	City        string `json:"city"`
+	StreetLine1 string `json:"street_line1"`
+	StreetLine2 string `json:"street_line2"`
+	PostCode    string `json:"post_code"`
+}
+
+type PreCheckoutQuery struct {
+	ID               string     `json:"id"`
+	From             User       `json:"from"`
+	Currency         string     `json:"currency"`
+	TotalAmount      int        `json:"total_amount"`
+	InvoicePayload   string     `json:"invoice_payload"`
+	ShippingOptionID string     `json:"shipping_option_id,omitempty"`
+	OrderInfo        *OrderInfo `json:"order_info,omitempty"`
+}
+
+type OrderInfo struct {
+	Name            string           `json:"name,omitempty"`
+	PhoneNumber     string           `json:"phone_number,omitempty"`
+	Email           string           `json:"email,omitempty"`
+	ShippingAddress *ShippingAddress `json:"shipping_address,omitempty"`
+}
+
+//
+// Polls
+//
+
+type Poll struct {
+	ID                    string          `json:"id"`
+	Question              string          `json:"question"`
+	Options               []PollOption    `json:"options"`
+	TotalVoterCount       int             `json:"total_voter_count"`
+	IsClosed              bool            `json:"is_closed"`
+	IsAnonymous           bool            `json:"is_anonymous"`
+	Type                  string          `json:"type"`
+	AllowsMultipleAnswers bool            `json:"allows_multiple_answers"`
+	CorrectOptionID       *int            `json:"correct_option_id,omitempty"`
+	Explanation           string          `json:"explanation,omitempty"`
+	ExplanationEntities   []MessageEntity `json:"explanation_entities,omitempty"`
+	OpenPeriod            int             `json:"open_period,omitempty"`
+	CloseDate             int64           `json:"close_date,omitempty"`
+}
+
+type PollOption struct {
+	Text       string `json:"text"`
+	VoterCount int    `json:"voter_count"`
+}
+
+type PollAnswer struct {
+	PollID    string `json:"poll_id"`
+	VoterChat *Chat  `json:"voter_chat,omitempty"`
+	User      *User  `json:"user,omitempty"`
+	OptionIDs []int  `json:"option_ids"`
+}
+
+//
+// Chat members
+//
+
+const (
+	ChatMemberCreator       = "creator"
+	ChatMemberAdministrator = "administrator"
+	ChatMemberMember        = "member"
+	ChatMemberRestricted    = "restricted"
+	ChatMemberLeft          = "left"
+	ChatMemberKicked        = "kicked"
+)
+
+type ChatMemberUpdated struct {
+	Chat                    Chat            `json:"chat"`
+	From                    User            `json:"from"`
+	Date                    int64           `json:"date"`
+	OldChatMember           ChatMember      `json:"old_chat_member"`
+	NewChatMember           ChatMember      `json:"new_chat_member"`
+	InviteLink              *ChatInviteLink `json:"invite_link,omitempty"`
+	ViaChatFolderInviteLink bool            `json:"via_chat_folder_invite_link,omitempty"`
+}
+
+// ChatMember contains fields of all kinds of chat members, kind is defined by Status
+type ChatMember struct {
+	Status      string `json:"status"`
+	User        User   `json:"user"`
+	IsAnonymous bool   `json:"is_anonymous,omitempty"`
+	CustomTitle string `json:"custom_title,omitempty"`
+	IsMember    bool   `json:"is_member,omitempty"`
+	UntilDate   int64  `json:"until_date,omitempty"`
+
+	CanBeEdited         bool `json:"can_be_edited,omitempty"`
+	CanManageChat       bool `json:"can_manage_chat,omitempty"`
+	CanDeleteMessages   bool `json:"can_delete_messages,omitempty"`
+	CanRestrictMembers  bool `json:"can_restrict_members,omitempty"`
+	CanPromoteMembers   bool `json:"can_promote_members,omitempty"`
+	CanChangeInfo       bool `json:"can_change_info,omitempty"`
+	CanInviteUsers      bool `json:"can_invite_users,omitempty"`
+	CanPostMessages     bool `json:"can_post_messages,omitempty"`
+	CanEditMessages     bool `json:"can_edit_messages,omitempty"`
+	CanPinMessages      bool `json:"can_pin_messages,omitempty"`
+	CanSendMessages     bool `json:"can_send_messages,omitempty"`
+	CanSendPolls        bool `json:"can_send_polls,omitempty"`
+	CanSendOtherMessage bool `json:"can_send_other_messages,omitempty"`
+}
+
+type ChatInviteLink struct {
+	InviteLink              string `json:"invite_link"`
+	Creator                 User   `json:"creator"`
+	CreatesJoinRequest      bool   `json:"creates_join_request"`
+	IsPrimary               bool   `json:"is_primary"`
+	IsRevoked               bool   `json:"is_revoked"`
+	Name                    string `json:"name,omitempty"`
+	ExpireDate              int64  `json:"expire_date,omitempty"`
+	MemberLimit             int    `json:"member_limit,omitempty"`
+	PendingJoinRequestCount int    `json:"pending_join_request_count,omitempty"`
+}
+
+type ChatJoinRequest struct {
+	Chat       Chat            `json:"chat"`
+	From       User            `json:"from"`
+	UserChatID int             `json:"user_chat_id"`
+	Date       int64           `json:"date"`
+	Bio        string          `json:"bio,omitempty"`
+	InviteLink *ChatInviteLink `json:"invite_link,omitempty"`
+}
+
+//
+// Reactions
+//
+
+type ReactionType struct {
+	Type          string `json:"type"`
+	Emoji         string `json:"emoji,omitempty"`
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+type MessageReactionUpdated struct {
+	Chat        Chat           `json:"chat"`
+	MessageID   int            `json:"message_id"`
+	User        *User          `json:"user,omitempty"`
+	ActorChat   *Chat          `json:"actor_chat,omitempty"`
+	Date        int64          `json:"date"`
+	OldReaction []ReactionType `json:"old_reaction"`
+	NewReaction []ReactionType `json:"new_reaction"`
+}
+
+type MessageReactionCountUpdated struct {
+	Chat      Chat            `json:"chat"`
+	MessageID int             `json:"message_id"`
+	Date      int64           `json:"date"`
+	Reactions []ReactionCount `json:"reactions"`
+}
+
+type ReactionCount struct {
+	Type       ReactionType `json:"type"`
+	TotalCount int          `json:"total_count"`
+}
+
 //
 // Files
 //
@@ -92,18 +348,134 @@ type File struct {
 }
 
 type Document struct {
-	FileID   string    `json:"file_id"`
-	Thumb    PhotoSize `json:"thumb,omitempty"`
-	FileName string    `json:"file_name,omitempty"`
-	MIMEType string    `json:"mime_type,omitempty"`
-	FileSize int       `json:"file_size,omitempty"`
+	FileID       string    `json:"file_id"`
+	FileUniqueID string    `json:"file_unique_id"`
+	Thumb        PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string    `json:"file_name,omitempty"`
+	MIMEType     string    `json:"mime_type,omitempty"`
+	FileSize     int       `json:"file_size,omitempty"`
 }
 
 type PhotoSize struct {
-	FileID   string `json:"file_id"`
-	Width    int    `json:"width"`
-	Height   int    `json:"height"`
-	FileSize int    `json:"file_size,omitempty"`
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int    `json:"file_size,omitempty"`
+}
+
+type Animation struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Duration     int        `json:"duration"`
+	Thumb        *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MIMEType     string     `json:"mime_type,omitempty"`
+	FileSize     int        `json:"file_size,omitempty"`
+}
+
+type Audio struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Duration     int        `json:"duration"`
+	Performer    string     `json:"performer,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MIMEType     string     `json:"mime_type,omitempty"`
+	FileSize     int        `json:"file_size,omitempty"`
+	Thumb        *PhotoSize `json:"thumbnail,omitempty"`
+}
+
+type Video struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Duration     int        `json:"duration"`
+	Thumb        *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MIMEType     string     `json:"mime_type,omitempty"`
+	FileSize     int        `json:"file_size,omitempty"`
+}
+
+type VideoNote struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Length       int        `json:"length"`
+	Duration     int        `json:"duration"`
+	Thumb        *PhotoSize `json:"thumbnail,omitempty"`
+	FileSize     int        `json:"file_size,omitempty"`
+}
+
+type Voice struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int    `json:"duration"`
+	MIMEType     string `json:"mime_type,omitempty"`
+	FileSize     int    `json:"file_size,omitempty"`
+}
+
+type Sticker struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Type         string     `json:"type"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	IsAnimated   bool       `json:"is_animated"`
+	IsVideo      bool       `json:"is_video"`
+	Thumb        *PhotoSize `json:"thumbnail,omitempty"`
+	Emoji        string     `json:"emoji,omitempty"`
+	SetName      string     `json:"set_name,omitempty"`
+	FileSize     int        `json:"file_size,omitempty"`
+}
+
+//
+// Message content
+//
+
+type Contact struct {
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name,omitempty"`
+	UserID      int    `json:"user_id,omitempty"`
+	VCard       string `json:"vcard,omitempty"`
+}
+
+type Venue struct {
+	Location        Location `json:"location"`
+	Title           string   `json:"title"`
+	Address         string   `json:"address"`
+	FoursquareID    string   `json:"foursquare_id,omitempty"`
+	FoursquareType  string   `json:"foursquare_type,omitempty"`
+	GooglePlaceID   string   `json:"google_place_id,omitempty"`
+	GooglePlaceType string   `json:"google_place_type,omitempty"`
+}
+
+type Dice struct {
+	Emoji string `json:"emoji"`
+	Value int    `json:"value"`
+}
+
+const (
+	MessageOriginUser       = "user"
+	MessageOriginHiddenUser = "hidden_user"
+	MessageOriginChat       = "chat"
+	MessageOriginChannel    = "channel"
+)
+
+// MessageOrigin describes the origin of forwarded message,
+// fields are filled depending on Type
+type MessageOrigin struct {
+	Type            string `json:"type"`
+	Date            int64  `json:"date"`
+	SenderUser      *User  `json:"sender_user,omitempty"`
+	SenderUserName  string `json:"sender_user_name,omitempty"`
+	SenderChat      *Chat  `json:"sender_chat,omitempty"`
+	Chat            *Chat  `json:"chat,omitempty"`
+	MessageID       int    `json:"message_id,omitempty"`
+	AuthorSignature string `json:"author_signature,omitempty"`
 }
 
 //
@@ -178,8 +550,14 @@ type InlineKeyboardButton struct {
 	URL          string    `json:"url,omitempty"`
 	LoginURL     *LoginURL `json:"login_url,omitempty"`
 	CallbackData string    `json:"callback_data,omitempty"`
-	// switch_inline_query
-	// switch_inline_query_current_chat
+
+	// SwitchInlineQuery prompt user to select chat and insert bot username
+	// with the query, empty query inserts only the username
+	SwitchInlineQuery *string `json:"switch_inline_query,omitempty"`
+
+	// SwitchInlineQueryCurrentChat insert bot username with the query in the current chat
+	SwitchInlineQueryCurrentChat *string `json:"switch_inline_query_current_chat,omitempty"`
+
 	// callback_game
 	// pay
 }
@@ -205,7 +583,7 @@ type ReplyKeyboardMarkup struct {
 type KeyboardButton struct {
 	Text            string `json:"text"`
 	RequestContact  bool   `json:"request_contact,omitempty"`
-	RequestLocation bool   `json:"request_contact,omitempty"`
+	RequestLocation bool   `json:"request_location,omitempty"`
 }
 
 type ReplyKeyboardRemove struct {
@@ -223,10 +601,15 @@ type ForceReply struct {
 //
 
 type InputMedia struct {
-	Type      string `json:"type"`
-	Media     string `json:"media"`
-	Caption   string `json:"caption,omitempty"`
-	ParseMode string `json:"parse_mode,omitempty"`
+	Type       string `json:"type"`
+	Media      string `json:"media"`
+	Thumbnail  string `json:"thumbnail,omitempty"`
+	Caption    string `json:"caption,omitempty"`
+	ParseMode  string `json:"parse_mode,omitempty"`
+	HasSpoiler bool   `json:"has_spoiler,omitempty"`
+
+	// File is source of the media, if it is empty then Media is used as is
+	File InputFile `json:"-"`
 }
 
 func (media InputMedia) ToJSON() string {
@@ -0,0 +1,81 @@
+package tebo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	data := `{
+		"update_id": 100,
+		"message": {
+			"message_id": 2,
+			"message_thread_id": 7,
+			"from": {"id": 1, "first_name": "Gopher"},
+			"chat": {"id": -100500, "type": "supergroup", "title": "team"},
+			"date": 1700000000,
+			"forward_origin": {"type": "user", "date": 1690000000, "sender_user": {"id": 9, "first_name": "Rob"}},
+			"reply_to_message": {
+				"message_id": 1,
+				"chat": {"id": -100500, "type": "supergroup"},
+				"text": "where are you?"
+			},
+			"caption": "here",
+			"caption_entities": [{"type": "bold", "offset": 0, "length": 4}],
+			"voice": {"file_id": "v1", "file_unique_id": "u1", "duration": 3, "mime_type": "audio/ogg"},
+			"location": {"latitude": 55.75, "longitude": 37.61, "live_period": 60},
+			"venue": {"location": {"latitude": 55.75, "longitude": 37.61}, "title": "Square", "address": "Center"},
+			"new_chat_members": [{"id": 5, "first_name": "New"}],
+			"migrate_to_chat_id": -100600
+		}
+	}`
+
+	var u Update
+	if err := json.Unmarshal([]byte(data), &u); err != nil {
+		t.Fatal(err)
+	}
+
+	m := u.Message
+	if m.ReplyToMessage == nil || m.ReplyToMessage.Text != "where are you?" {
+		t.Errorf("reply is not decoded: %+v", m.ReplyToMessage)
+	}
+	if m.Voice == nil || m.Voice.Duration != 3 || m.Location == nil || m.Location.LivePeriod != 60 {
+		t.Errorf("media is not decoded: %+v %+v", m.Voice, m.Location)
+	}
+	if m.ForwardOrigin == nil || m.ForwardOrigin.SenderUser.FirstName != "Rob" {
+		t.Errorf("forward origin is not decoded: %+v", m.ForwardOrigin)
+	}
+
+	packed, err := msgpack.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unpacked Update
+	if err := msgpack.Unmarshal(packed, &unpacked); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(u, unpacked) {
+		t.Errorf("update is changed after msgpack round trip:\n%+v\n%+v", u, unpacked)
+	}
+}
+
+func TestContextPoll(t *testing.T) {
+	b := newOfflineBot(t)
+
+	poll := &Poll{ID: "p1", Question: "why?"}
+
+	ctx := b.newContext(Update{Message: Message{MessageID: 1, Chat: Chat{ID: 1}, Poll: poll}})
+	if ctx.Poll != poll {
+		t.Errorf("poll of the message is not set: %+v", ctx.Poll)
+	}
+
+	ctx = b.newContext(Update{Poll: poll})
+	if ctx.Poll != poll {
+		t.Errorf("poll of the update is not set: %+v", ctx.Poll)
+	}
+}
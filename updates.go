@@ -0,0 +1,274 @@
+package tebo
+
+// Kinds of updates, used as allowed_updates values
+const (
+	UpdateMessage              = "message"
+	UpdateEditedMessage        = "edited_message"
+	UpdateChannelPost          = "channel_post"
+	UpdateEditedChannelPost    = "edited_channel_post"
+	UpdateMessageReaction      = "message_reaction"
+	UpdateMessageReactionCount = "message_reaction_count"
+	UpdateInlineQuery          = "inline_query"
+	UpdateChosenInlineResult   = "chosen_inline_result"
+	UpdateCallbackQuery        = "callback_query"
+	UpdateShippingQuery        = "shipping_query"
+	UpdatePreCheckoutQuery     = "pre_checkout_query"
+	UpdatePoll                 = "poll"
+	UpdatePollAnswer           = "poll_answer"
+	UpdateMyChatMember         = "my_chat_member"
+	UpdateChatMember           = "chat_member"
+	UpdateChatJoinRequest      = "chat_join_request"
+)
+
+// AllowedUpdates is list of update kinds requested by GetUpdates,
+// chat_member and reactions are not sent by telegram unless requested explicitly
+var AllowedUpdates = []string{
+	UpdateMessage,
+	UpdateEditedMessage,
+	UpdateChannelPost,
+	UpdateEditedChannelPost,
+	UpdateMessageReaction,
+	UpdateMessageReactionCount,
+	UpdateInlineQuery,
+	UpdateChosenInlineResult,
+	UpdateCallbackQuery,
+	UpdateShippingQuery,
+	UpdatePreCheckoutQuery,
+	UpdatePoll,
+	UpdatePollAnswer,
+	UpdateMyChatMember,
+	UpdateChatMember,
+	UpdateChatJoinRequest,
+}
+
+// Kind return kind of the update
+func (u Update) Kind() string {
+	switch {
+	case u.EditedMessage != nil:
+		return UpdateEditedMessage
+	case u.ChannelPost != nil:
+		return UpdateChannelPost
+	case u.EditedChannelPost != nil:
+		return UpdateEditedChannelPost
+	case u.MessageReaction != nil:
+		return UpdateMessageReaction
+	case u.MessageReactionCount != nil:
+		return UpdateMessageReactionCount
+	case u.InlineQuery != nil:
+		return UpdateInlineQuery
+	case u.ChosenInlineResult != nil:
+		return UpdateChosenInlineResult
+	case u.CallbackQuery != nil:
+		return UpdateCallbackQuery
+	case u.ShippingQuery != nil:
+		return UpdateShippingQuery
+	case u.PreCheckoutQuery != nil:
+		return UpdatePreCheckoutQuery
+	case u.Poll != nil:
+		return UpdatePoll
+	case u.PollAnswer != nil:
+		return UpdatePollAnswer
+	case u.MyChatMember != nil:
+		return UpdateMyChatMember
+	case u.ChatMember != nil:
+		return UpdateChatMember
+	case u.ChatJoinRequest != nil:
+		return UpdateChatJoinRequest
+	}
+
+	return UpdateMessage
+}
+
+// updateMessage return message of the update, if update contains it
+func updateMessage(u Update) Message {
+	switch {
+	case u.EditedMessage != nil:
+		return *u.EditedMessage
+	case u.ChannelPost != nil:
+		return *u.ChannelPost
+	case u.EditedChannelPost != nil:
+		return *u.EditedChannelPost
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.Message
+	}
+
+	return u.Message
+}
+
+// updateChat return chat the update belongs to,
+// updates from inline mode, payments and polls have no chat
+func updateChat(u Update) Chat {
+	switch {
+	case u.MessageReaction != nil:
+		return u.MessageReaction.Chat
+	case u.MessageReactionCount != nil:
+		return u.MessageReactionCount.Chat
+	case u.MyChatMember != nil:
+		return u.MyChatMember.Chat
+	case u.ChatMember != nil:
+		return u.ChatMember.Chat
+	case u.ChatJoinRequest != nil:
+		return u.ChatJoinRequest.Chat
+	}
+
+	return updateMessage(u).Chat
+}
+
+// updateFrom return user initiated the update
+func updateFrom(u Update) User {
+	switch {
+	case u.MessageReaction != nil && u.MessageReaction.User != nil:
+		return *u.MessageReaction.User
+	case u.InlineQuery != nil:
+		return u.InlineQuery.From
+	case u.ChosenInlineResult != nil:
+		return u.ChosenInlineResult.From
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.From
+	case u.ShippingQuery != nil:
+		return u.ShippingQuery.From
+	case u.PreCheckoutQuery != nil:
+		return u.PreCheckoutQuery.From
+	case u.PollAnswer != nil && u.PollAnswer.User != nil:
+		return *u.PollAnswer.User
+	case u.MyChatMember != nil:
+		return u.MyChatMember.From
+	case u.ChatMember != nil:
+		return u.ChatMember.From
+	case u.ChatJoinRequest != nil:
+		return u.ChatJoinRequest.From
+	}
+
+	return updateMessage(u).From
+}
+
+// updateDate return unix time of the update, it is zero for inline queries,
+// payments and polls
+func updateDate(u Update) int64 {
+	switch {
+	case u.MessageReaction != nil:
+		return u.MessageReaction.Date
+	case u.MessageReactionCount != nil:
+		return u.MessageReactionCount.Date
+	case u.MyChatMember != nil:
+		return u.MyChatMember.Date
+	case u.ChatMember != nil:
+		return u.ChatMember.Date
+	case u.ChatJoinRequest != nil:
+		return u.ChatJoinRequest.Date
+	}
+
+	return updateMessage(u).Date
+}
+
+// updateKey return key used to process updates sequentially,
+// it is chat id or id of user if update has no chat
+func updateKey(u Update) int {
+	if chat := updateChat(u); chat.ID != 0 {
+		return chat.ID
+	}
+
+	return updateFrom(u).ID
+}
+
+// On register handler for updates of specified kind, except messages and
+// callback queries which are routed by Handle and FSM, if handler returns
+// message then it is sent to the chat of the update
+func (b *Bot) On(kind string, f HandleFunc, mid ...MiddlewareFunc) {
+	if b.kindHandlers == nil {
+		b.kindHandlers = make(map[string]handler)
+	}
+
+	b.kindHandlers[kind] = handler{cmd: kind, callback: f, middlewares: mid}
+}
+
+func (b *Bot) OnEditedMessage(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateEditedMessage, f, mid...)
+}
+
+func (b *Bot) OnChannelPost(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateChannelPost, f, mid...)
+}
+
+func (b *Bot) OnEditedChannelPost(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateEditedChannelPost, f, mid...)
+}
+
+func (b *Bot) OnMessageReaction(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateMessageReaction, f, mid...)
+}
+
+func (b *Bot) OnMessageReactionCount(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateMessageReactionCount, f, mid...)
+}
+
+func (b *Bot) OnInlineQuery(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateInlineQuery, f, mid...)
+}
+
+func (b *Bot) OnChosenInlineResult(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateChosenInlineResult, f, mid...)
+}
+
+func (b *Bot) OnShippingQuery(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateShippingQuery, f, mid...)
+}
+
+func (b *Bot) OnPreCheckoutQuery(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdatePreCheckoutQuery, f, mid...)
+}
+
+func (b *Bot) OnPoll(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdatePoll, f, mid...)
+}
+
+func (b *Bot) OnPollAnswer(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdatePollAnswer, f, mid...)
+}
+
+func (b *Bot) OnMyChatMember(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateMyChatMember, f, mid...)
+}
+
+func (b *Bot) OnChatMember(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateChatMember, f, mid...)
+}
+
+func (b *Bot) OnChatJoinRequest(f HandleFunc, mid ...MiddlewareFunc) {
+	b.On(UpdateChatJoinRequest, f, mid...)
+}
+
+// routeKind pass update to the handler of its kind,
+// return false if update is a message or a callback query
+func (b *Bot) routeKind(ctx *Context) bool {
+	kind := ctx.Kind()
+
+	if len(ctx.MediaGroup) > 0 {
+		if h, ok := b.kindHandlers[UpdateMediaGroup]; ok {
+			if err := b.execute(ctx, h); err != nil {
+				log.Errorf("failed to handle media group: %v", err)
+			}
+			return true
+		}
+	}
+
+	if kind == UpdateMessage || kind == UpdateCallbackQuery {
+		return false
+	}
+
+	if kind == UpdateInlineQuery && b.routeInline(ctx) {
+		return true
+	}
+
+	h, ok := b.kindHandlers[kind]
+	if !ok {
+		log.Debugf("handler for %s is not registered", kind)
+		return true
+	}
+
+	if err := b.execute(ctx, h); err != nil {
+		log.Errorf("failed to handle %s: %v", kind, err)
+	}
+
+	return true
+}
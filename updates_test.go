@@ -0,0 +1,66 @@
+package tebo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdateKinds(t *testing.T) {
+	b := newOfflineBot(t)
+
+	got := make(map[string]*Context)
+	record := func(ctx *Context) *SendMessage {
+		got[ctx.Kind()] = ctx
+		return nil
+	}
+
+	b.Handle(".*", record)
+	b.OnEditedMessage(record)
+	b.OnChatMember(record)
+	b.OnPoll(record)
+
+	updates := []string{
+		`{"update_id":1,"edited_message":{"message_id":5,"chat":{"id":10,"type":"private"},"text":"fixed"}}`,
+		`{"update_id":2,"chat_member":{"chat":{"id":-20,"type":"group","title":"team"},"from":{"id":3},"old_chat_member":{"status":"left","user":{"id":4}},"new_chat_member":{"status":"member","user":{"id":4}}}}`,
+		`{"update_id":3,"poll":{"id":"p1","question":"?","options":[{"text":"a","voter_count":1}]}}`,
+	}
+
+	for _, data := range updates {
+		var u Update
+		if err := json.Unmarshal([]byte(data), &u); err != nil {
+			t.Fatal(err)
+		}
+
+		b.updateHistory([]Update{u})
+		b.route(u)
+	}
+
+	if _, ok := got[UpdateMessage]; ok {
+		t.Error("edited message is routed to command handler")
+	}
+
+	if ctx, ok := got[UpdateEditedMessage]; !ok || ctx.Text != "fixed" || ctx.Chat.ID != 10 {
+		t.Errorf("edited message is not routed: %+v", ctx)
+	}
+
+	if ctx, ok := got[UpdateChatMember]; !ok || ctx.Chat.ID != -20 || ctx.ChatMember.NewChatMember.Status != ChatMemberMember {
+		t.Errorf("chat member update is not routed: %+v", ctx)
+	}
+
+	if ctx, ok := got[UpdatePoll]; !ok || ctx.Poll.ID != "p1" {
+		t.Errorf("poll is not routed: %+v", ctx)
+	}
+
+	var n int
+	b.Chats.Range(func(id int, _ string) bool {
+		if id == 0 {
+			t.Error("chat with zero id is registered")
+		}
+		n++
+		return true
+	})
+
+	if n != 2 {
+		t.Errorf("expected 2 chats, got %d", n)
+	}
+}
@@ -0,0 +1,102 @@
+package tebo
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// SecretTokenHeader is the header used by telegram to pass the secret token
+// specified on the setWebhook call
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+type ReqSetWebhook struct {
+	URL                string   `json:"url"`
+	IPAddress          string   `json:"ip_address,omitempty"`
+	MaxConnections     int      `json:"max_connections,omitempty"`
+	AllowedUpdates     []string `json:"allowed_updates,omitempty"`
+	DropPendingUpdates bool     `json:"drop_pending_updates,omitempty"`
+	SecretToken        string   `json:"secret_token,omitempty"`
+}
+
+type WebhookInfo struct {
+	URL                          string   `json:"url"`
+	HasCustomCertificate         bool     `json:"has_custom_certificate"`
+	PendingUpdateCount           int      `json:"pending_update_count"`
+	IPAddress                    string   `json:"ip_address,omitempty"`
+	LastErrorDate                int64    `json:"last_error_date,omitempty"`
+	LastErrorMessage             string   `json:"last_error_message,omitempty"`
+	LastSynchronizationErrorDate int64    `json:"last_synchronization_error_date,omitempty"`
+	MaxConnections               int      `json:"max_connections,omitempty"`
+	AllowedUpdates               []string `json:"allowed_updates,omitempty"`
+}
+
+// SetWebhook register url to receive incoming updates,
+// while webhook is set the GetUpdates method does not work
+func (b *Bot) SetWebhook(wh ReqSetWebhook) error {
+	var resp bool
+	return b.Request("setWebhook", wh, &resp)
+}
+
+// DeleteWebhook remove webhook integration to switch back to GetUpdates
+func (b *Bot) DeleteWebhook(dropPendingUpdates bool) error {
+	var resp bool
+	return b.Request("deleteWebhook", map[string]interface{}{
+		"drop_pending_updates": dropPendingUpdates,
+	}, &resp)
+}
+
+func (b *Bot) GetWebhookInfo() (info WebhookInfo, err error) {
+	err = b.Request("getWebhookInfo", nil, &info)
+	return
+}
+
+// Webhook is http.Handler receiving updates pushed by telegram,
+// it is an alternative to the polling loop of the Start method
+type Webhook struct {
+	bot    *Bot
+	secret string
+}
+
+// WebhookHandler return handler for incoming updates, if secret is not empty
+// then each request should contain it in the X-Telegram-Bot-Api-Secret-Token header
+func (b *Bot) WebhookHandler(secret string) *Webhook {
+	return &Webhook{bot: b, secret: secret}
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if wh.secret != "" {
+		token := r.Header.Get(SecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(wh.secret)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if wh.bot.closed.Load() {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	var u Update
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// on error telegram will repeat this update later
+	if err := wh.bot.updateHistory([]Update{u}); err != nil {
+		log.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	wh.bot.dispatcher.dispatch(u)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,71 @@
+package tebo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newOfflineBot(t *testing.T) *Bot {
+	b := newBot(testToken)
+	if err := b.readHistory(filepath.Join(t.TempDir(), "history")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.closeHistory() })
+
+	return b
+}
+
+func TestWebhook(t *testing.T) {
+	b := newOfflineBot(t)
+
+	received := make(chan *Context, 1)
+	b.Handle("/ping", func(ctx *Context) *SendMessage {
+		received <- ctx
+		return nil
+	})
+
+	srv := httptest.NewServer(b.WebhookHandler("secret"))
+	defer srv.Close()
+
+	body := `{"update_id":42,"message":{"message_id":1,"chat":{"id":7,"type":"private","username":"gopher"},"text":"/ping"}}`
+
+	post := func(secret string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		req.Header.Set(SecretTokenHeader, secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %s", resp.Status)
+	}
+
+	if resp := post("secret"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %s", resp.Status)
+	}
+
+	select {
+	case ctx := <-received:
+		if ctx.Chat.ID != 7 {
+			t.Errorf("invalid chat id %d", ctx.Chat.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler is not called")
+	}
+
+	if b.UpdateID != 42 {
+		t.Errorf("update id is not stored, got %d", b.UpdateID)
+	}
+
+	if id, ok := b.LookupChatID("gopher"); !ok || id != 7 {
+		t.Errorf("chat is not registered: %d %v", id, ok)
+	}
+}